@@ -0,0 +1,24 @@
+package main
+
+import _ "embed"
+
+// The contract ABIs live in abis/ as JSON and are compiled into the binary.
+// NewEthereumClient parses them.
+
+//go:embed abis/pair.json
+var pairABI string
+
+//go:embed abis/erc20.json
+var erc20ABI string
+
+//go:embed abis/factory.json
+var factoryABI string
+
+//go:embed abis/multicall3.json
+var multicall3ABI string
+
+//go:embed abis/quoterv3.json
+var quoterV3ABI string
+
+//go:embed abis/router.json
+var routerABI string
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// accessLogMiddleware logs one line per HTTP request with its method, path,
+// status, response size and duration. Like requestIDMiddleware it wraps the
+// whole router, so 404s, 405s and rejected requests are logged too. With a
+// slowThreshold, successful requests faster than it are logged at debug.
+func accessLogMiddleware(next http.Handler, slowThreshold time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &accessLogRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		elapsed := time.Since(start)
+		level := slog.LevelInfo
+		if status < http.StatusBadRequest && fastRequest(elapsed, slowThreshold) {
+			level = slog.LevelDebug
+		}
+		slog.Log(r.Context(), level, "http request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"bytes", rec.bytes,
+			"duration_ms", elapsed.Milliseconds(),
+			"client_ip", clientIP(r),
+		)
+	})
+}
+
+// accessLogRecorder captures the status and bytes written. It counts what
+// reaches the wire, so gzipped responses report their compressed size.
+type accessLogRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rec *accessLogRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *accessLogRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(p)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Hijack lets WebSocket upgrades take over the connection, which the
+// upgrader only does through a type assertion.
+func (rec *accessLogRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(rec.ResponseWriter).Hijack()
+	if err == nil {
+		rec.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *accessLogRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var ErrInvalidAddress = errors.New("invalid address format")
+
+// parseAddress is a strict replacement for common.HexToAddress, which
+// silently pads or truncates malformed input. The input must be a 0x-prefixed
+// 40 hex character string, and mixed-case input must carry a valid EIP-55
+// checksum.
+func parseAddress(field, s string) (common.Address, error) {
+	if len(s) != 42 || !strings.HasPrefix(s, "0x") || !common.IsHexAddress(s) {
+		return common.Address{}, fmt.Errorf("%w for %s: %q", ErrInvalidAddress, field, s)
+	}
+
+	addr := common.HexToAddress(s)
+
+	hexPart := s[2:]
+	if hexPart != strings.ToLower(hexPart) && hexPart != strings.ToUpper(hexPart) && addr.Hex() != s {
+		return common.Address{}, fmt.Errorf("%w for %s: %q fails EIP-55 checksum", ErrInvalidAddress, field, s)
+	}
+
+	return addr, nil
+}
+
+// parseAddressList parses a comma-separated list of addresses, skipping empty
+// entries.
+func parseAddressList(field, s string) ([]common.Address, error) {
+	parts := strings.Split(s, ",")
+	addrs := make([]common.Address, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		addr, err := parseAddress(field, part)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var ErrPoolNotAllowed = errors.New("pool not allowed")
+
+// parsePoolAllowlist reads POOL_ALLOWLIST; see readPoolList for the format.
+func parsePoolAllowlist(value string) (map[common.Address]bool, error) {
+	pools, err := readPoolList(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(pools) == 0 {
+		return nil, errors.New("pool allowlist is empty")
+	}
+	return pools, nil
+}
+
+// readPoolList parses either a comma-separated list of pool addresses or the
+// path of a file listing them. A file may separate addresses with commas or
+// whitespace and use # for comments. Entries are parsed like request
+// addresses, so one with a bad EIP-55 checksum is rejected.
+func readPoolList(value string) (map[common.Address]bool, error) {
+	list := value
+	if first, _, _ := strings.Cut(value, ","); !common.IsHexAddress(strings.TrimSpace(first)) {
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pool list: %w", err)
+		}
+
+		var lines []string
+		for _, line := range strings.Split(string(data), "\n") {
+			line, _, _ = strings.Cut(line, "#")
+			lines = append(lines, line)
+		}
+		list = strings.Join(lines, ",")
+	}
+
+	pools := make(map[common.Address]bool)
+	for _, entry := range strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r'
+	}) {
+		pool, err := parseAddress("pool", entry)
+		if err != nil {
+			return nil, err
+		}
+		pools[pool] = true
+	}
+	return pools, nil
+}
+
+// SetPoolAllowlist restricts estimates to the given pools. A nil allowlist
+// allows every pool.
+func (se *SwapEstimator) SetPoolAllowlist(pools map[common.Address]bool) {
+	se.poolAllowlist = pools
+}
+
+// checkPoolAllowed rejects denylisted pools and pools outside the allowlist
+// before any node call is made for them. The denylist wins when a pool is on
+// both.
+func (se *SwapEstimator) checkPoolAllowed(poolAddr common.Address) error {
+	if se.poolDenylist.contains(poolAddr) {
+		return fmt.Errorf("%w: %s is on this server's pool denylist", ErrPoolDenied, poolAddr.Hex())
+	}
+	if se.poolAllowlist != nil && !se.poolAllowlist[poolAddr] {
+		return fmt.Errorf("%w: %s is not on this server's pool allowlist", ErrPoolNotAllowed, poolAddr.Hex())
+	}
+	return nil
+}
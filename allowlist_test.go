@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestReadPoolList(t *testing.T) {
+	usdcWETH := common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc")
+	daiWETH := common.HexToAddress("0xA478c2975Ab1Ea89e8196811F51A7B7Ade33eB11")
+
+	file := filepath.Join(t.TempDir(), "pools.txt")
+	content := "# USDC/WETH\n0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc\n0xa478c2975ab1ea89e8196811f51a7b7ade33eb11 # DAI/WETH\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []string{
+		"0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc,0xA478c2975Ab1Ea89e8196811F51A7B7Ade33eB11",
+		// All-lowercase addresses carry no checksum
+		"0xb4e16d0168e52d35cacd2c6185b44281ec28c9dc, 0xa478c2975ab1ea89e8196811f51a7b7ade33eb11",
+		file,
+	} {
+		pools, err := readPoolList(value)
+		if err != nil {
+			t.Errorf("readPoolList(%q): %v", value, err)
+			continue
+		}
+		if len(pools) != 2 || !pools[usdcWETH] || !pools[daiWETH] {
+			t.Errorf("readPoolList(%q) = %v, want USDC/WETH and DAI/WETH", value, pools)
+		}
+	}
+}
+
+func TestReadPoolListRejectsBadEntries(t *testing.T) {
+	for _, value := range []string{
+		// Bad EIP-55 checksum: the last letter's case is flipped
+		"0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9DC",
+		"0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc,0xA478c2975Ab1Ea89e8196811F51A7B7Ade33eB1",
+		"0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc,b4e16d0168e52d35cacd2c6185b44281ec28c9dc",
+	} {
+		if _, err := readPoolList(value); !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("readPoolList(%q) err = %v, want %v", value, err, ErrInvalidAddress)
+		}
+	}
+}
+
+func TestGetPoolStatesChecksAllowlist(t *testing.T) {
+	otherPool := common.HexToAddress("0xA478c2975Ab1Ea89e8196811F51A7B7Ade33eB11")
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool:  {testToken0, testToken1, testReserves(t)},
+		otherPool: {testToken0, testToken1, testReserves(t)},
+	})
+	se := NewSwapEstimator(chain)
+	se.SetPoolAllowlist(map[common.Address]bool{testPool: true})
+
+	states, errs := se.GetPoolStates(context.Background(), []common.Address{otherPool, testPool})
+	if !errors.Is(errs[0], ErrPoolNotAllowed) || states[0] != nil {
+		t.Errorf("pool outside the allowlist: state %v, err %v, want %v", states[0], errs[0], ErrPoolNotAllowed)
+	}
+	if errs[1] != nil || states[1] == nil || states[1].Token0 != testToken0 {
+		t.Errorf("allowed pool: state %v, err %v", states[1], errs[1])
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// GetAllowance returns how much of token spender may move on owner's behalf.
+func (ec *EthereumClient) GetAllowance(ctx context.Context, token, owner, spender common.Address, blockNumber *big.Int) (*big.Int, error) {
+	data, err := ec.erc20ABI.Pack("allowance", owner, spender)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack allowance call: %w", err)
+	}
+
+	result, err := ec.callContract(ctx, "allowance", token, data, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call allowance: %w", err)
+	}
+
+	unpacked, err := ec.erc20ABI.Unpack("allowance", result)
+	if err != nil || len(unpacked) != 1 {
+		return nil, fmt.Errorf("%w: failed to unpack allowance result", errMalformedResult)
+	}
+
+	allowance, ok := unpacked[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("%w: failed to cast allowance to *big.Int", errMalformedResult)
+	}
+
+	return allowance, nil
+}
+
+// checkApproval sets needs_approval and allowance on response for the
+// request's owner, with the router as spender. Native ETH is sent with the
+// swap, so it never needs approval. A failed check is reported as a warning
+// rather than failing the estimate.
+func (se *SwapEstimator) checkApproval(ctx context.Context, params *estimateParams, response *EstimateResponse) {
+	if params.tokens.WrapSrc {
+		needsApproval := false
+		response.NeedsApproval = &needsApproval
+		return
+	}
+
+	allowance, err := se.ethClient.GetAllowance(ctx, params.tokens.Src, *params.owner, se.router, params.opts.BlockNumber)
+	if err != nil {
+		slog.DebugContext(ctx, "allowance check failed", "token", params.tokens.Src.Hex(), "owner", params.owner.Hex(), "error", err)
+		response.Warnings = append(response.Warnings, fmt.Sprintf("Could not read the allowance of src token %s", params.tokens.Src.Hex()))
+		return
+	}
+
+	needsApproval := allowance.Cmp(params.srcAmount) < 0
+	response.NeedsApproval = &needsApproval
+	response.Allowance = allowance.String()
+}
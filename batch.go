@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	batchWorkers = 10
+	maxBatchSize = 100
+)
+
+type BatchEstimateResult struct {
+	DstAmount string `json:"dst_amount,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Code      string `json:"code,omitempty"`
+}
+
+// batchItemError reports err for one item with the code it would get from
+// /estimate. Like writeEstimateError, it doesn't pass on the text of server
+// and node errors.
+func batchItemError(status int, err error) BatchEstimateResult {
+	message := err.Error()
+	if isTimeout(err) {
+		message = "Timed out waiting for the Ethereum node"
+	} else if status >= http.StatusInternalServerError {
+		message = "Failed to estimate swap"
+	}
+	return BatchEstimateResult{Error: message, Code: errorCode(status, err)}
+}
+
+// batchPoolKey identifies a pool on the chain a batch item selected.
+type batchPoolKey struct {
+	chainID string
+	pool    common.Address
+}
+
+// batchPoolState is a prefetched pool read; at most one field is set.
+type batchPoolState struct {
+	state *PoolState
+	err   error
+}
+
+// prefetchPoolStates reads the pools of all items with one GetPoolStates
+// batch per chain, instead of a round trip per item. Items with an invalid
+// pool or chain_id are skipped here and rejected when they are estimated.
+func (se *SwapEstimator) prefetchPoolStates(ctx context.Context, reqs []EstimateRequest) map[batchPoolKey]batchPoolState {
+	byChain := make(map[string][]common.Address)
+	seen := make(map[batchPoolKey]bool)
+	for _, req := range reqs {
+		pool, err := parseAddress("pool", req.Pool)
+		if err != nil {
+			continue
+		}
+		key := batchPoolKey{req.ChainID, pool}
+		if !seen[key] {
+			seen[key] = true
+			byChain[req.ChainID] = append(byChain[req.ChainID], pool)
+		}
+	}
+
+	prefetched := make(map[batchPoolKey]batchPoolState, len(seen))
+	for chainID, pools := range byChain {
+		chainEstimator, err := se.forChain(chainID)
+		if err != nil {
+			continue
+		}
+		states, errs := chainEstimator.GetPoolStates(ctx, pools)
+		for i, pool := range pools {
+			prefetched[batchPoolKey{chainID, pool}] = batchPoolState{state: states[i], err: errs[i]}
+		}
+	}
+	return prefetched
+}
+
+// EstimateBatch reads every item's pool up front, then runs each request
+// through EstimateSwapWithOptions on a bounded worker pool. Results are
+// returned in the same order as reqs.
+func (se *SwapEstimator) EstimateBatch(ctx context.Context, reqs []EstimateRequest) []BatchEstimateResult {
+	results := make([]BatchEstimateResult, len(reqs))
+	prefetched := se.prefetchPoolStates(ctx, reqs)
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(batchWorkers, len(reqs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = se.estimateBatchItem(ctx, reqs[i], prefetched)
+			}
+		}()
+	}
+
+	for i := range reqs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func (se *SwapEstimator) estimateBatchItem(ctx context.Context, req EstimateRequest, prefetched map[batchPoolKey]batchPoolState) BatchEstimateResult {
+	if req.Pool == "" || req.Src == "" || req.Dst == "" || req.SrcAmount == "" {
+		return BatchEstimateResult{Error: "Missing required parameters: pool, src, dst, src_amount", Code: CodeMissingParameter}
+	}
+
+	srcAmount, err := se.parseSrcAmount(req.SrcAmount)
+	if err != nil {
+		return batchItemError(http.StatusBadRequest, err)
+	}
+
+	se, err = se.forChain(req.ChainID)
+	if errors.Is(err, ErrChainNotConfigured) {
+		return batchItemError(http.StatusBadRequest, err)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "batch item estimate failed", "chain_id", req.ChainID, "error", err)
+		return BatchEstimateResult{Error: "Failed to connect to chain", Code: errorCode(http.StatusInternalServerError, err)}
+	}
+
+	poolAddr, err := parseAddress("pool", req.Pool)
+	if err != nil {
+		return batchItemError(http.StatusBadRequest, err)
+	}
+
+	// Native ETH is quoted through WETH, as in /estimate
+	tokens, err := se.resolveSwapTokens(req.Src, req.Dst)
+	if err != nil {
+		return batchItemError(http.StatusBadRequest, err)
+	}
+
+	// Each item may target a different fork, so fee_bps is per item
+	opts := se.DefaultOptions()
+	if req.FeeBps != "" {
+		feeBps, err := strconv.ParseInt(req.FeeBps, 10, 64)
+		if err != nil || feeBps < 0 || feeBps > 10000 {
+			return BatchEstimateResult{Error: "Invalid fee_bps: must be an integer between 0 and 10000", Code: CodeInvalidParameter}
+		}
+		opts.Fee = SwapFeeFromBps(feeBps)
+	}
+
+	pool := prefetched[batchPoolKey{req.ChainID, poolAddr}]
+	err = pool.err
+	var estimate *SwapEstimate
+	if err == nil {
+		opts.PoolState = pool.state
+		estimate, err = se.EstimateSwapWithOptions(ctx, poolAddr, tokens.Src, tokens.Dst, srcAmount, opts)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "batch item estimate failed", "pool", req.Pool, "src", req.Src, "dst", req.Dst, "src_amount", req.SrcAmount, "error", err)
+		return batchItemError(estimateErrorStatus(err), err)
+	}
+
+	return BatchEstimateResult{DstAmount: estimate.AmountOut.String()}
+}
+
+// wantsBatchCSV reports whether the batch results should be written as CSV,
+// either because format=csv was passed or because Accept lists text/csv
+// before application/json. Quality values are ignored.
+func wantsBatchCSV(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("format") {
+	case "csv":
+		return true, nil
+	case "json":
+		return false, nil
+	case "":
+	default:
+		return false, errors.New("Invalid format: must be json or csv")
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return true, nil
+		case "application/json":
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+// writeBatchCSV writes one row per request, in order. Failed items leave
+// amount_out empty and report the error in the last column.
+func writeBatchCSV(w http.ResponseWriter, reqs []EstimateRequest, results []BatchEstimateResult) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"pool", "src", "dst", "amount_in", "amount_out", "error"})
+	for i, req := range reqs {
+		cw.Write([]string{req.Pool, req.Src, req.Dst, req.SrcAmount, results[i].DstAmount, results[i].Error})
+	}
+	cw.Flush()
+}
+
+func (se *SwapEstimator) estimateBatchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	asCSV, err := wantsBatchCSV(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, err.Error())
+		return
+	}
+
+	var reqs []EstimateRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Invalid JSON body: expected an array of estimate requests")
+		return
+	}
+
+	if len(reqs) > maxBatchSize {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, fmt.Sprintf("Batch too large: at most %d requests allowed", maxBatchSize))
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	results := se.EstimateBatch(ctx, reqs)
+	if asCSV {
+		writeBatchCSV(w, reqs, results)
+		return
+	}
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBatchItemErrorHidesServerErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		err         error
+		wantMessage string
+		wantCode    string
+	}{
+		{"client error", http.StatusBadRequest, errSameToken, errSameToken.Error(), CodeSameToken},
+		{"node error", http.StatusBadGateway, fmt.Errorf("%w: dial tcp 10.0.0.1:8545: connection refused", ErrRPCFailure), "Failed to estimate swap", CodeRPCError},
+		{"internal error", http.StatusInternalServerError, errMalformedResult, "Failed to estimate swap", CodeInternalError},
+		{"timeout", http.StatusGatewayTimeout, context.DeadlineExceeded, "Timed out waiting for the Ethereum node", CodeTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := batchItemError(tt.status, tt.err)
+			if got.Error != tt.wantMessage || got.Code != tt.wantCode {
+				t.Errorf("got %q (%s), want %q (%s)", got.Error, got.Code, tt.wantMessage, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestEstimateBatchItemAcceptsETH(t *testing.T) {
+	pool := common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc")
+	usdc := common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	reserveUSDC := big.NewInt(25_000_000_000_000)
+	reserveWETH := bigInt(t, "10000000000000000000000")
+
+	prefetched := map[batchPoolKey]batchPoolState{
+		{pool: pool}: {state: &PoolState{
+			PoolReserves: &PoolReserves{Reserve0: reserveUSDC, Reserve1: reserveWETH},
+			Token0:       usdc,
+			Token1:       defaultWETHAddress,
+		}},
+	}
+
+	se := NewSwapEstimator(nil)
+	srcAmount := bigInt(t, "1000000000000000000")
+	got := se.estimateBatchItem(context.Background(), EstimateRequest{
+		Pool:      pool.Hex(),
+		Src:       "ETH",
+		Dst:       usdc.Hex(),
+		SrcAmount: srcAmount.String(),
+	}, prefetched)
+
+	want := calculateSwapAmount(srcAmount, reserveWETH, reserveUSDC, DefaultSwapFee)
+	if got.Error != "" || got.DstAmount != want.String() {
+		t.Errorf("got %+v, want dst_amount %s", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const maxCandidatePools = 20
+
+type PoolCandidate struct {
+	Pool        string `json:"pool"`
+	DstAmount   string `json:"dst_amount,omitempty"`
+	PriceImpact string `json:"price_impact,omitempty"`
+	// Error explains why the pool was skipped
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+type EstimateBestResponse struct {
+	Pool      string `json:"pool"`
+	DstAmount string `json:"dst_amount"`
+	// Candidates ranks every pool by dst_amount, best first, followed by the
+	// pools that couldn't be quoted
+	Candidates  []PoolCandidate `json:"candidates"`
+	WrapsETH    bool            `json:"wraps_eth,omitempty"`
+	UnwrapsWETH bool            `json:"unwraps_weth,omitempty"`
+}
+
+// poolEstimate is one candidate's outcome; exactly one of estimate and err
+// is set.
+type poolEstimate struct {
+	pool     common.Address
+	estimate *SwapEstimate
+	err      error
+}
+
+// EstimateBestPool estimates the swap through every pool, reading their
+// state in one batch, and returns the outcomes ranked by output, best first.
+// Pools that fail are kept, after all successful ones, in the order given.
+func (se *SwapEstimator) EstimateBestPool(ctx context.Context, pools []common.Address, srcToken, dstToken common.Address, srcAmount *big.Int) []poolEstimate {
+	results := make([]poolEstimate, len(pools))
+
+	states, errs := se.GetPoolStates(ctx, pools)
+	for i, pool := range pools {
+		if errs[i] != nil {
+			results[i] = poolEstimate{pool: pool, err: errs[i]}
+			continue
+		}
+
+		opts := se.DefaultOptions()
+		opts.PoolState = states[i]
+		estimate, err := se.EstimateSwapWithOptions(ctx, pool, srcToken, dstToken, srcAmount, opts)
+		results[i] = poolEstimate{pool: pool, estimate: estimate, err: err}
+	}
+
+	slices.SortStableFunc(results, func(a, b poolEstimate) int {
+		switch {
+		case a.err != nil && b.err != nil:
+			return 0
+		case a.err != nil:
+			return 1
+		case b.err != nil:
+			return -1
+		}
+		return b.estimate.AmountOut.Cmp(a.estimate.AmountOut)
+	})
+
+	return results
+}
+
+func (se *SwapEstimator) estimateBestHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	poolsStr := query.Get("pools")
+	srcStr := query.Get("src")
+	dstStr := query.Get("dst")
+	srcAmountStr := query.Get("src_amount")
+
+	if poolsStr == "" || srcStr == "" || dstStr == "" || srcAmountStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameters: pools, src, dst, src_amount")
+		return
+	}
+
+	pools, err := parseAddressList("pools", poolsStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+	pools = uniqueAddresses(pools)
+
+	if len(pools) == 0 || len(pools) > maxCandidatePools {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("Invalid pools: must list between 1 and %d pool addresses", maxCandidatePools))
+		return
+	}
+
+	se, err = se.forChain(query.Get("chain_id"))
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if !errors.Is(err, ErrChainNotConfigured) {
+			slog.ErrorContext(r.Context(), "best pool estimate failed", "pools", poolsStr, "error", err)
+			status, message = http.StatusInternalServerError, "Failed to connect to chain"
+		}
+		writeError(w, status, errorCode(status, err), message)
+		return
+	}
+
+	tokens, err := se.resolveSwapTokens(srcStr, dstStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	srcAmount, err := se.parseSrcAmount(srcAmountStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	results := se.EstimateBestPool(ctx, pools, tokens.Src, tokens.Dst, srcAmount)
+
+	best := results[0]
+	if best.err != nil {
+		// Every pool failed; report the first failure as the cause
+		slog.WarnContext(ctx, "best pool estimate failed", "pools", poolsStr, "src", srcStr, "dst", dstStr, "src_amount", srcAmountStr, "error", best.err)
+		writeEstimateError(w, fmt.Errorf("no candidate pool could quote this swap: %w", best.err))
+		return
+	}
+
+	response := EstimateBestResponse{
+		Pool:        best.pool.Hex(),
+		DstAmount:   best.estimate.AmountOut.String(),
+		Candidates:  make([]PoolCandidate, len(results)),
+		WrapsETH:    tokens.WrapSrc,
+		UnwrapsWETH: tokens.UnwrapDst,
+	}
+	for i, result := range results {
+		candidate := PoolCandidate{Pool: result.pool.Hex()}
+		if result.err != nil {
+			slog.DebugContext(ctx, "skipping candidate pool", "pool", result.pool.Hex(), "error", result.err)
+			candidate.Error = estimateErrorMessage(result.err)
+			candidate.Code = errorCode(estimateErrorStatus(result.err), result.err)
+		} else {
+			candidate.DstAmount = result.estimate.AmountOut.String()
+			candidate.PriceImpact = result.estimate.PriceImpact.FloatString(4)
+		}
+		response.Candidates[i] = candidate
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// uniqueAddresses drops repeated addresses, keeping the first occurrence.
+func uniqueAddresses(addrs []common.Address) []common.Address {
+	seen := make(map[common.Address]bool, len(addrs))
+	unique := addrs[:0]
+	for _, addr := range addrs {
+		if !seen[addr] {
+			seen[addr] = true
+			unique = append(unique, addr)
+		}
+	}
+	return unique
+}
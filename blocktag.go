@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// blockTags maps the block tags accepted in BLOCK_TAG and block_tag to the
+// negative block numbers ethclient sends as those tags.
+var blockTags = map[string]rpc.BlockNumber{
+	"latest":    rpc.LatestBlockNumber,
+	"safe":      rpc.SafeBlockNumber,
+	"finalized": rpc.FinalizedBlockNumber,
+}
+
+// parseBlockTag returns the block number standing for tag, or nil for
+// latest, which is what a nil block already means.
+func parseBlockTag(tag string) (*big.Int, error) {
+	number, ok := blockTags[tag]
+	if !ok {
+		return nil, fmt.Errorf("unknown block tag %q", tag)
+	}
+	if number == rpc.LatestBlockNumber {
+		return nil, nil
+	}
+	return big.NewInt(int64(number)), nil
+}
+
+// SetBlockTag makes calls that don't ask for a specific block read the state
+// at tag instead of the latest block. A nil tag means latest.
+func (ec *EthereumClient) SetBlockTag(tag *big.Int) {
+	ec.blockTag = tag
+}
+
+func (ec *EthereumClient) blockOrDefault(blockNumber *big.Int) *big.Int {
+	if blockNumber == nil {
+		return ec.blockTag
+	}
+	return blockNumber
+}
+
+// formatBlock renders a block number for errors, showing tags by name.
+func formatBlock(blockNumber *big.Int) string {
+	if blockNumber.Sign() < 0 && blockNumber.IsInt64() {
+		return rpc.BlockNumber(blockNumber.Int64()).String()
+	}
+	return blockNumber.String()
+}
+
+// parseBlockParams parses the block and block_tag request parameters, which
+// are mutually exclusive. It returns nil when neither is set.
+func parseBlockParams(block, blockTag string) (*big.Int, error) {
+	if block != "" && blockTag != "" {
+		return nil, errors.New("Invalid block_tag: can't be combined with block")
+	}
+
+	if block != "" {
+		blockNumber, ok := new(big.Int).SetString(block, 10)
+		if !ok || blockNumber.Sign() < 0 {
+			return nil, errors.New("Invalid block: must be a non-negative block number")
+		}
+		return blockNumber, nil
+	}
+
+	if blockTag != "" {
+		if _, ok := blockTags[blockTag]; !ok {
+			return nil, errors.New("Invalid block_tag: must be latest, safe or finalized")
+		}
+		// An explicit latest overrides a BLOCK_TAG default, so it can't be nil
+		return big.NewInt(int64(blockTags[blockTag])), nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrNodeUnavailable is returned without contacting the node while its
+// circuit breaker is open.
+var ErrNodeUnavailable = errors.New("ethereum node unavailable")
+
+type breakerState int
+
+// The values are exported as the estimator_circuit_breaker_state gauge.
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+var (
+	breakerStateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "estimator_circuit_breaker_state",
+		Help: "Ethereum node circuit breaker state: 0 closed, 1 half-open, 2 open.",
+	}, []string{"node"})
+
+	breakerTripsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "estimator_circuit_breaker_trips_total",
+		Help: "Number of times the Ethereum node circuit breaker opened.",
+	}, []string{"node"})
+)
+
+// circuitBreaker stops sending calls to a node after threshold consecutive
+// failures. Once cooldown has passed a single probe call is let through; its
+// outcome closes the breaker or opens it for another cooldown. A nil
+// *circuitBreaker lets every call through.
+type circuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(name string, threshold int, cooldown time.Duration) *circuitBreaker {
+	breakerStateGauge.WithLabelValues(name).Set(float64(breakerClosed))
+	return &circuitBreaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow returns ErrNodeUnavailable if the call must not reach the node.
+func (cb *circuitBreaker) Allow() error {
+	if cb == nil {
+		return nil
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case breakerOpen:
+		if wait := cb.cooldown - time.Since(cb.openedAt); wait > 0 {
+			return fmt.Errorf("%w: circuit breaker open for another %s", ErrNodeUnavailable, (wait + time.Second - 1).Truncate(time.Second))
+		}
+		// This caller is the probe
+		cb.setState(breakerHalfOpen)
+	case breakerHalfOpen:
+		return fmt.Errorf("%w: circuit breaker probing the node", ErrNodeUnavailable)
+	}
+	return nil
+}
+
+// Record counts the outcome of a call that Allow let through.
+func (cb *circuitBreaker) Record(err error) {
+	if cb == nil {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if !isNodeFailure(err) {
+		cb.failures = 0
+		if cb.state != breakerClosed {
+			cb.setState(breakerClosed)
+		}
+		return
+	}
+
+	cb.failures++
+	if cb.state == breakerHalfOpen || (cb.state == breakerClosed && cb.failures >= cb.threshold) {
+		cb.openedAt = time.Now()
+		cb.setState(breakerOpen)
+		breakerTripsTotal.WithLabelValues(cb.name).Inc()
+	}
+}
+
+func (cb *circuitBreaker) setState(state breakerState) {
+	cb.state = state
+	breakerStateGauge.WithLabelValues(cb.name).Set(float64(state))
+}
+
+// isNodeFailure reports whether err means the node itself is unhealthy.
+// Reverts, missing historical state and callers giving up don't count.
+func isNodeFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return !isContractFailure(err) && !isMissingStateError(err)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// pairCallMethods are the pair calls an estimate makes, in order.
+var pairCallMethods = []string{"getReserves", "token0", "token1"}
+
+// PackedCall is an eth_call an estimate would make, as sent to the node.
+type PackedCall struct {
+	Method string `json:"method"`
+	To     string `json:"to"`
+	Data   string `json:"data"`
+}
+
+// CalldataResponse is returned instead of an estimate for debug=calldata.
+type CalldataResponse struct {
+	Calls []PackedCall `json:"calls"`
+	// Block is the block the calls would be made against; empty means latest
+	Block string `json:"block,omitempty"`
+}
+
+// packPairCall ABI-encodes a call to one of the pair's argument-less methods.
+func (ec *EthereumClient) packPairCall(method string) ([]byte, error) {
+	data, err := ec.abi.Pack(method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s call: %w", method, err)
+	}
+	return data, nil
+}
+
+// PairCalldata returns the calls GetReserves, GetToken0 and GetToken1 would
+// make to pairAddr, without contacting the node.
+func (ec *EthereumClient) PairCalldata(pairAddr common.Address) ([]PackedCall, error) {
+	calls := make([]PackedCall, 0, len(pairCallMethods))
+	for _, method := range pairCallMethods {
+		data, err := ec.packPairCall(method)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, PackedCall{
+			Method: method,
+			To:     pairAddr.Hex(),
+			Data:   hexutil.Encode(data),
+		})
+	}
+	return calls, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	chainNodeURLPrefix  = "ETH_NODE_URL_"
+	chainWETHPrefix     = "WETH_ADDRESS_"
+	chainFactoryPrefix  = "FACTORY_ADDRESS_"
+	chainRouterPrefix   = "ROUTER_ADDRESS_"
+	chainQuoterV3Prefix = "QUOTER_V3_ADDRESS_"
+)
+
+var ErrChainNotConfigured = errors.New("chain not configured")
+
+// ChainContracts are the contracts used on an additional chain, from
+// WETH_ADDRESS_<chainID>, FACTORY_ADDRESS_<chainID>, ROUTER_ADDRESS_<chainID>
+// and QUOTER_V3_ADDRESS_<chainID>.
+type ChainContracts struct {
+	WETH     common.Address
+	Factory  common.Address
+	Router   common.Address
+	QuoterV3 common.Address
+}
+
+// ChainClients holds one EthereumClient per configured chain. Clients are
+// dialed lazily the first time a chain is requested.
+type ChainClients struct {
+	mu        sync.Mutex
+	urls      map[uint64]string
+	contracts map[uint64]ChainContracts
+	clients   map[uint64]*EthereumClient
+
+	maxRetries          int
+	breakerThreshold    int
+	breakerCooldown     time.Duration
+	reservesFromStorage bool
+	reservesCacheTTL    time.Duration
+	blockTag            *big.Int
+}
+
+func NewChainClients(urls map[uint64]string, contracts map[uint64]ChainContracts) *ChainClients {
+	return &ChainClients{
+		urls:       urls,
+		contracts:  contracts,
+		clients:    make(map[uint64]*EthereumClient),
+		maxRetries: defaultRPCMaxRetries,
+	}
+}
+
+// SetMaxRetries applies to clients dialed after the call.
+func (cc *ChainClients) SetMaxRetries(maxRetries int) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.maxRetries = maxRetries
+}
+
+// SetCircuitBreaker applies to clients dialed after the call. Each chain
+// gets its own breaker, labelled with its chain ID.
+func (cc *ChainClients) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.breakerThreshold = threshold
+	cc.breakerCooldown = cooldown
+}
+
+// SetReservesFromStorage applies to clients dialed after the call.
+func (cc *ChainClients) SetReservesFromStorage(fromStorage bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.reservesFromStorage = fromStorage
+}
+
+// SetReservesCacheTTL applies to clients dialed after the call.
+func (cc *ChainClients) SetReservesCacheTTL(ttl time.Duration) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.reservesCacheTTL = ttl
+}
+
+// SetBlockTag applies to clients dialed after the call.
+func (cc *ChainClients) SetBlockTag(tag *big.Int) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.blockTag = tag
+}
+
+// chainContractSettings are the per-chain contract addresses, each read from
+// <prefix><chainID>.
+var chainContractSettings = []struct {
+	prefix string
+	field  func(*ChainContracts) *common.Address
+}{
+	{chainWETHPrefix, func(c *ChainContracts) *common.Address { return &c.WETH }},
+	{chainFactoryPrefix, func(c *ChainContracts) *common.Address { return &c.Factory }},
+	{chainRouterPrefix, func(c *ChainContracts) *common.Address { return &c.Router }},
+	{chainQuoterV3Prefix, func(c *ChainContracts) *common.Address { return &c.QuoterV3 }},
+}
+
+// parseChainEnv collects every ETH_NODE_URL_<chainID> variable and the
+// per-chain contract addresses from environ, reporting all malformed ones.
+func parseChainEnv(environ []string) (map[uint64]string, map[uint64]ChainContracts, []string) {
+	urls := make(map[uint64]string)
+	contracts := make(map[uint64]ChainContracts)
+	var problems []string
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		if value == "" {
+			continue
+		}
+
+		if strings.HasPrefix(key, chainNodeURLPrefix) {
+			chainID, err := strconv.ParseUint(strings.TrimPrefix(key, chainNodeURLPrefix), 10, 64)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s must end in a numeric chain ID", key))
+				continue
+			}
+			urls[chainID] = value
+			continue
+		}
+
+		for _, setting := range chainContractSettings {
+			if !strings.HasPrefix(key, setting.prefix) {
+				continue
+			}
+			chainID, err := strconv.ParseUint(strings.TrimPrefix(key, setting.prefix), 10, 64)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s must end in a numeric chain ID", key))
+				break
+			}
+			addr, err := parseAddress(key, value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s must be a 0x-prefixed address with a valid EIP-55 checksum, got %q", key, value))
+				break
+			}
+			c := contracts[chainID]
+			*setting.field(&c) = addr
+			contracts[chainID] = c
+			break
+		}
+	}
+
+	return urls, contracts, problems
+}
+
+// Contracts returns the contract addresses configured for chainID. The
+// mainnet defaults don't apply to other chains, so unset ones are zero.
+func (cc *ChainClients) Contracts(chainID uint64) ChainContracts {
+	return cc.contracts[chainID]
+}
+
+func (cc *ChainClients) Get(chainID uint64) (*EthereumClient, error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if client, ok := cc.clients[chainID]; ok {
+		return client, nil
+	}
+
+	url, ok := cc.urls[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrChainNotConfigured, chainID)
+	}
+
+	client, err := NewEthereumClient(url)
+	if err != nil {
+		return nil, fmt.Errorf("chain %d: %w", chainID, err)
+	}
+	client.SetMaxRetries(cc.maxRetries)
+	client.SetCircuitBreaker(strconv.FormatUint(chainID, 10), cc.breakerThreshold, cc.breakerCooldown)
+	client.SetReservesFromStorage(cc.reservesFromStorage)
+	client.SetReservesCacheTTL(cc.reservesCacheTTL)
+	client.SetBlockTag(cc.blockTag)
+	cc.clients[chainID] = client
+
+	return client, nil
+}
+
+func (cc *ChainClients) Close() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	for _, client := range cc.clients {
+		client.Close()
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParseChainEnv(t *testing.T) {
+	urls, contracts, problems := parseChainEnv([]string{
+		"ETH_NODE_URL_42161=https://arb.example",
+		"WETH_ADDRESS_42161=0x82aF49447D8a07e3bd95BD0d56f35241523fBab1",
+		"FACTORY_ADDRESS_42161=0xf1D7CC64Fb4452F05c498126312eBE29f30Fbcf9",
+		"ROUTER_ADDRESS_42161=0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24",
+		"QUOTER_V3_ADDRESS_137=0x61fFE014bA17989E743c5F6cB21bF9697530B21e",
+		"FACTORY_ADDRESS=0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f",
+		"ROUTER_ADDRESS_arb=0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24",
+		"FACTORY_ADDRESS_10=not-an-address",
+		"WETH_ADDRESS_10=4200000000000000000000000000000000000006",
+		// Bad EIP-55 checksum: one letter's case is flipped
+		"ROUTER_ADDRESS_8453=0x4752ba5DBc23f44D87826276BF6Fd6b1C372ad24",
+	})
+
+	if urls[42161] != "https://arb.example" {
+		t.Errorf("urls[42161] = %q", urls[42161])
+	}
+	want := ChainContracts{
+		WETH:    common.HexToAddress("0x82aF49447D8a07e3bd95BD0d56f35241523fBab1"),
+		Factory: common.HexToAddress("0xf1D7CC64Fb4452F05c498126312eBE29f30Fbcf9"),
+		Router:  common.HexToAddress("0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24"),
+	}
+	if contracts[42161] != want {
+		t.Errorf("contracts[42161] = %+v, want %+v", contracts[42161], want)
+	}
+	if contracts[137].QuoterV3 != defaultQuoterV3Address {
+		t.Errorf("contracts[137].QuoterV3 = %s", contracts[137].QuoterV3.Hex())
+	}
+	if len(problems) != 4 {
+		t.Errorf("problems = %q, want 4", problems)
+	}
+}
+
+// TestForChainDropsMainnetContracts checks that a chain without its own
+// addresses doesn't fall back to the mainnet defaults.
+func TestForChainDropsMainnetContracts(t *testing.T) {
+	arbFactory := common.HexToAddress("0xf1D7CC64Fb4452F05c498126312eBE29f30Fbcf9")
+	chains := NewChainClients(
+		map[uint64]string{42161: "http://arb.invalid"},
+		map[uint64]ChainContracts{42161: {Factory: arbFactory}},
+	)
+	// Skip dialing; forChain only needs a client to exist
+	chains.clients[42161] = &EthereumClient{}
+
+	se := NewSwapEstimator(nil)
+	se.SetChainClients(chains)
+
+	arb, err := se.forChain("42161")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arb.factory != arbFactory {
+		t.Errorf("factory = %s, want %s", arb.factory.Hex(), arbFactory.Hex())
+	}
+	for name, addr := range map[string]common.Address{"router": arb.router, "quoterV3": arb.quoterV3, "weth": arb.weth} {
+		if addr != (common.Address{}) {
+			t.Errorf("%s = %s, want unset", name, addr.Hex())
+		}
+	}
+
+	if _, status, err := arb.parseEstimateRequest(EstimateRequest{
+		Pool:      common.HexToAddress("0x0d4a11d5eeaac28ec3f61d100daf4d40471f1852").Hex(),
+		Src:       common.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7").Hex(),
+		Dst:       defaultWETHAddress.Hex(),
+		SrcAmount: "1000",
+		Engine:    engineRouter,
+	}); err == nil || status != 400 || !strings.Contains(err.Error(), "no router address") {
+		t.Errorf("engine=router on a chain without a router: status %d, err %v", status, err)
+	}
+}
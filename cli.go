@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+// cliResponse collects what a handler writes so the estimate command can
+// reuse the HTTP code path unchanged.
+type cliResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (cr *cliResponse) Header() http.Header { return cr.header }
+
+func (cr *cliResponse) Write(b []byte) (int, error) { return cr.body.Write(b) }
+
+func (cr *cliResponse) WriteHeader(status int) { cr.status = status }
+
+// runEstimateCommand runs `estimator estimate`, which prints one estimate as
+// JSON on stdout. It returns the process exit code: 1 if the estimate failed
+// and 2 for bad usage.
+func runEstimateCommand(args []string) int {
+	fs := flag.NewFlagSet("estimate", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: estimator estimate --pool POOL --src SRC [--dst DST] --amount AMOUNT [flags]")
+		fs.PrintDefaults()
+	}
+
+	var req EstimateRequest
+	fs.StringVar(&req.Pool, "pool", "", "pair address")
+	fs.StringVar(&req.Src, "src", "", "input token address, or ETH")
+	fs.StringVar(&req.Dst, "dst", "", "output token address, or ETH; defaults to the pool's other token")
+	fs.StringVar(&req.SrcAmount, "amount", "", "input amount in the src token's base units")
+	fs.StringVar(&req.FeeBps, "fee-bps", "", "LP fee in basis points")
+	fs.StringVar(&req.Block, "block", "", "block number to quote against")
+	fs.StringVar(&req.BlockTag, "block-tag", "", "latest, safe or finalized")
+	fs.StringVar(&req.Format, "format", "", "raw (default) or decimal")
+	fs.StringVar(&req.Precision, "precision", "", "decimal places shown with --format decimal")
+	fs.StringVar(&req.ChainID, "chain-id", "", "chain to quote on")
+	fs.StringVar(&req.SlippageBps, "slippage-bps", "", "slippage tolerance in basis points")
+	fs.StringVar(&req.IntegratorFeeBps, "integrator-fee-bps", "", "integrator fee in basis points")
+	fs.StringVar(&req.Engine, "engine", "", "local (default) or router")
+	fs.StringVar(&req.Curve, "curve", "", "constant_product (default) or stableswap")
+	fs.StringVar(&req.Amplification, "amplification", "", "StableSwap amplification coefficient A")
+	fs.StringVar(&req.NumberFormat, "number-format", "", "string (default) or number")
+	fs.StringVar(&req.Owner, "owner", "", "wallet to check the router's src allowance for")
+	fs.StringVar(&req.CheckRebasing, "check-rebasing", "", "true to flag reserves that grew on one side only")
+	fs.StringVar(&req.Revalidate, "revalidate", "", "true to read fresh reserves and report the block they came from")
+	fs.StringVar(&req.Envelope, "envelope", "", "true to wrap the result with chain and block metadata")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected argument %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	godotenv.Load()
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	// Logs go to stderr so stdout carries only the result
+	slog.SetDefault(newLogger(os.Stderr, max(cfg.LogLevel, slog.LevelWarn)))
+
+	ethClient, chains, estimator, err := newEstimatorFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer ethClient.Close()
+	defer chains.Close()
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "/estimate", nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	resp := &cliResponse{header: make(http.Header), status: http.StatusOK}
+	estimator.serveEstimate(resp, r, req)
+	os.Stdout.Write(resp.body.Bytes())
+
+	if resp.status >= http.StatusBadRequest {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	defaultPort            = "1337"
+	defaultShutdownTimeout = 10 * time.Second
+
+	defaultHTTPReadTimeout  = 10 * time.Second
+	defaultHTTPWriteTimeout = 30 * time.Second
+	defaultHTTPIdleTimeout  = 120 * time.Second
+)
+
+// Config is every setting the server reads from the environment, validated
+// once at startup.
+type Config struct {
+	NodeURL    string
+	ListenAddr string
+	LogLevel   slog.Level
+	// GRPCListenAddr is where the gRPC server listens, alongside the REST
+	// server on ListenAddr
+	GRPCListenAddr string
+	// AccessLog logs one line per HTTP request
+	AccessLog bool
+	// SlowRequestThreshold is 0 when every request is logged at info
+	SlowRequestThreshold time.Duration
+
+	CORSOrigins []string
+	// RateLimitRPS is 0 when rate limiting is disabled
+	RateLimitRPS   float64
+	RateLimitBurst int
+	// MaxInFlight is 0 when load shedding is disabled
+	MaxInFlight     int
+	ShutdownTimeout time.Duration
+	// HTTPReadTimeout, HTTPWriteTimeout and HTTPIdleTimeout bound each
+	// client connection; 0 disables a timeout
+	HTTPReadTimeout  time.Duration
+	HTTPWriteTimeout time.Duration
+	HTTPIdleTimeout  time.Duration
+
+	RPCMaxRetries int
+	RPCTimeout    time.Duration
+	// BreakerThreshold is 0 when the circuit breaker is disabled
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// ReservesFromStorage reads reserves with eth_getStorageAt
+	ReservesFromStorage bool
+	// BlockTag is the block read when a request doesn't name one; nil means
+	// latest
+	BlockTag *big.Int
+	// ReservesCacheTTL is 0 when reserves aren't cached
+	ReservesCacheTTL time.Duration
+	// ResponseCacheTTL is 0 when estimate responses aren't cached
+	ResponseCacheTTL time.Duration
+	// ChainNodeURLs come from ETH_NODE_URL_<chainID>, and ChainContracts
+	// from the per-chain contract address variables
+	ChainNodeURLs  map[uint64]string
+	ChainContracts map[uint64]ChainContracts
+
+	Fee     SwapFee
+	WETH    common.Address
+	Factory common.Address
+	// PoolAllowlist is nil unless POOL_ALLOWLIST is set
+	PoolAllowlist map[common.Address]bool
+	// PoolDenylist is nil unless POOL_DENYLIST is set
+	PoolDenylist *poolDenylist
+	// PreloadPools are read at startup to warm the caches
+	PreloadPools []common.Address
+	// FactoryFees comes from FACTORY_FEES, e.g. 0xFACTORY:25,0xFACTORY:30
+	FactoryFees  map[common.Address]SwapFee
+	QuoterV3     common.Address
+	Router       common.Address
+	MaxSrcAmount *big.Int
+	// USDPricePool is the WETH/stablecoin pair behind liquidity_usd; zero
+	// when unset
+	USDPricePool common.Address
+	// SwapGasLimit and PriorityFee price the swap for include_gas
+	SwapGasLimit      uint64
+	PriorityFee       *big.Int
+	AllowNodeOverride bool
+}
+
+// LoadConfig reads the configuration from the environment. Rather than
+// stopping at the first bad value, it reports every missing or invalid
+// setting in one error.
+func LoadConfig() (*Config, error) {
+	cfg := &Config{
+		CORSOrigins:      parseCORSOrigins(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		RateLimitBurst:   defaultRateLimitBurst,
+		MaxInFlight:      defaultMaxInFlight,
+		AccessLog:        true,
+		ShutdownTimeout:  defaultShutdownTimeout,
+		HTTPReadTimeout:  defaultHTTPReadTimeout,
+		HTTPWriteTimeout: defaultHTTPWriteTimeout,
+		HTTPIdleTimeout:  defaultHTTPIdleTimeout,
+		RPCMaxRetries:    defaultRPCMaxRetries,
+		RPCTimeout:       defaultRPCTimeout,
+		BreakerThreshold: defaultBreakerThreshold,
+		BreakerCooldown:  defaultBreakerCooldown,
+		Fee:              DefaultSwapFee,
+		WETH:             defaultWETHAddress,
+		Factory:          defaultFactoryAddress,
+		QuoterV3:         defaultQuoterV3Address,
+		Router:           defaultRouterAddress,
+		MaxSrcAmount:     defaultMaxSrcAmount,
+		SwapGasLimit:     defaultSwapGasLimit,
+		PriorityFee:      defaultPriorityFee,
+	}
+
+	var problems []string
+	invalid := func(name, want, value string) {
+		problems = append(problems, fmt.Sprintf("%s must be %s, got %q", name, want, value))
+	}
+
+	cfg.NodeURL = os.Getenv("ETH_NODE_URL")
+	if cfg.NodeURL == "" {
+		problems = append(problems, "ETH_NODE_URL is required")
+	}
+
+	cfg.ListenAddr = os.Getenv("LISTEN_ADDR")
+	if cfg.ListenAddr == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = defaultPort
+		}
+		cfg.ListenAddr = ":" + port
+	}
+	if err := validateListenAddr(cfg.ListenAddr); err != nil {
+		invalid("LISTEN_ADDR/PORT", "host:port, e.g. 127.0.0.1:1337", cfg.ListenAddr)
+	}
+
+	cfg.GRPCListenAddr = os.Getenv("GRPC_LISTEN_ADDR")
+	if cfg.GRPCListenAddr == "" {
+		cfg.GRPCListenAddr = defaultGRPCListenAddr
+	}
+	if err := validateListenAddr(cfg.GRPCListenAddr); err != nil {
+		invalid("GRPC_LISTEN_ADDR", "host:port, e.g. 127.0.0.1:50051", cfg.GRPCListenAddr)
+	} else if cfg.GRPCListenAddr == cfg.ListenAddr {
+		problems = append(problems, fmt.Sprintf("GRPC_LISTEN_ADDR must differ from the REST address %s", cfg.ListenAddr))
+	}
+
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		level, err := parseLogLevel(v)
+		if err != nil {
+			invalid("LOG_LEVEL", "debug, info, warn or error", v)
+		}
+		cfg.LogLevel = level
+	}
+
+	if v := os.Getenv("ACCESS_LOG"); v != "" {
+		accessLog, err := strconv.ParseBool(v)
+		if err != nil {
+			invalid("ACCESS_LOG", "true or false", v)
+		}
+		cfg.AccessLog = accessLog
+	}
+
+	if v := os.Getenv("SLOW_REQUEST_THRESHOLD"); v != "" {
+		threshold, err := time.ParseDuration(v)
+		if err != nil || threshold < 0 {
+			invalid("SLOW_REQUEST_THRESHOLD", "a duration such as 500ms", v)
+		}
+		cfg.SlowRequestThreshold = threshold
+	}
+
+	if v := os.Getenv("RATE_LIMIT_RPS"); v != "" {
+		rps, err := strconv.ParseFloat(v, 64)
+		if err != nil || rps < 0 {
+			invalid("RATE_LIMIT_RPS", "a non-negative number", v)
+		}
+		cfg.RateLimitRPS = rps
+	}
+
+	if v := os.Getenv("RATE_LIMIT_BURST"); v != "" {
+		burst, err := strconv.Atoi(v)
+		if err != nil || burst < 1 {
+			invalid("RATE_LIMIT_BURST", "a positive integer", v)
+		}
+		cfg.RateLimitBurst = burst
+	}
+
+	if v := os.Getenv("MAX_IN_FLIGHT"); v != "" {
+		maxInFlight, err := strconv.Atoi(v)
+		if err != nil || maxInFlight < 0 {
+			invalid("MAX_IN_FLIGHT", "a non-negative integer", v)
+		}
+		cfg.MaxInFlight = maxInFlight
+	}
+
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout < 0 {
+			invalid("SHUTDOWN_TIMEOUT", "a duration such as 10s", v)
+		}
+		cfg.ShutdownTimeout = timeout
+	}
+
+	for _, setting := range []struct {
+		name    string
+		timeout *time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", &cfg.HTTPReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", &cfg.HTTPWriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", &cfg.HTTPIdleTimeout},
+	} {
+		if v := os.Getenv(setting.name); v != "" {
+			timeout, err := time.ParseDuration(v)
+			if err != nil || timeout < 0 {
+				invalid(setting.name, "a duration such as 30s", v)
+			}
+			*setting.timeout = timeout
+		}
+	}
+
+	if v := os.Getenv("RPC_MAX_RETRIES"); v != "" {
+		retries, err := strconv.Atoi(v)
+		if err != nil || retries < 0 {
+			invalid("RPC_MAX_RETRIES", "a non-negative integer", v)
+		}
+		cfg.RPCMaxRetries = retries
+	}
+
+	if v := os.Getenv("RPC_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			invalid("RPC_TIMEOUT", "a positive duration such as 5s", v)
+		}
+		cfg.RPCTimeout = timeout
+	}
+
+	if v := os.Getenv("CIRCUIT_BREAKER_THRESHOLD"); v != "" {
+		threshold, err := strconv.Atoi(v)
+		if err != nil || threshold < 0 {
+			invalid("CIRCUIT_BREAKER_THRESHOLD", "a non-negative integer", v)
+		}
+		cfg.BreakerThreshold = threshold
+	}
+
+	if v := os.Getenv("CIRCUIT_BREAKER_COOLDOWN"); v != "" {
+		cooldown, err := time.ParseDuration(v)
+		if err != nil || cooldown <= 0 {
+			invalid("CIRCUIT_BREAKER_COOLDOWN", "a positive duration such as 30s", v)
+		}
+		cfg.BreakerCooldown = cooldown
+	}
+
+	if v := os.Getenv("RESERVES_FROM_STORAGE"); v != "" {
+		fromStorage, err := strconv.ParseBool(v)
+		if err != nil {
+			invalid("RESERVES_FROM_STORAGE", "true or false", v)
+		}
+		cfg.ReservesFromStorage = fromStorage
+	}
+
+	if v := os.Getenv("BLOCK_TAG"); v != "" {
+		tag, err := parseBlockTag(v)
+		if err != nil {
+			invalid("BLOCK_TAG", "latest, safe or finalized", v)
+		}
+		cfg.BlockTag = tag
+	}
+
+	if v := os.Getenv("RESERVES_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl < 0 {
+			invalid("RESERVES_CACHE_TTL", "a non-negative duration such as 2s", v)
+		}
+		cfg.ReservesCacheTTL = ttl
+	}
+
+	if v := os.Getenv("RESPONSE_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl < 0 {
+			invalid("RESPONSE_CACHE_TTL", "a non-negative duration such as 1s", v)
+		}
+		cfg.ResponseCacheTTL = ttl
+	}
+
+	var chainProblems []string
+	cfg.ChainNodeURLs, cfg.ChainContracts, chainProblems = parseChainEnv(os.Environ())
+	problems = append(problems, chainProblems...)
+
+	if v := os.Getenv("DEFAULT_FEE_BPS"); v != "" {
+		feeBps, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || feeBps < 0 || feeBps >= 10000 {
+			invalid("DEFAULT_FEE_BPS", "an integer from 0 to 9999", v)
+		}
+		cfg.Fee = SwapFeeFromBps(feeBps)
+	}
+
+	for _, setting := range []struct {
+		name string
+		addr *common.Address
+	}{
+		{"WETH_ADDRESS", &cfg.WETH},
+		{"FACTORY_ADDRESS", &cfg.Factory},
+		{"QUOTER_V3_ADDRESS", &cfg.QuoterV3},
+		{"ROUTER_ADDRESS", &cfg.Router},
+		{"USD_PRICE_POOL", &cfg.USDPricePool},
+	} {
+		if v := os.Getenv(setting.name); v != "" {
+			addr, err := parseAddress(setting.name, v)
+			if err != nil {
+				invalid(setting.name, "a 0x-prefixed address with a valid EIP-55 checksum", v)
+				continue
+			}
+			*setting.addr = addr
+		}
+	}
+
+	if v := os.Getenv("FACTORY_FEES"); v != "" {
+		fees, err := parseFactoryFees(v)
+		if err != nil {
+			invalid("FACTORY_FEES", "comma-separated factory:bps pairs", v)
+		}
+		cfg.FactoryFees = fees
+	}
+
+	if v := os.Getenv("POOL_ALLOWLIST"); v != "" {
+		pools, err := parsePoolAllowlist(v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("POOL_ALLOWLIST must be comma-separated pool addresses or a readable file of them: %v", err))
+		}
+		cfg.PoolAllowlist = pools
+	}
+
+	if v := os.Getenv("POOL_DENYLIST"); v != "" {
+		denylist, err := newPoolDenylist(v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("POOL_DENYLIST must be comma-separated pool addresses or a readable file of them: %v", err))
+		}
+		cfg.PoolDenylist = denylist
+	}
+
+	if v := os.Getenv("PRELOAD_POOLS"); v != "" {
+		pools, err := parseAddressList("PRELOAD_POOLS", v)
+		if err != nil {
+			invalid("PRELOAD_POOLS", "comma-separated pool addresses", v)
+		}
+		cfg.PreloadPools = uniqueAddresses(pools)
+	}
+
+	if v := os.Getenv("MAX_SRC_AMOUNT"); v != "" {
+		maxSrcAmount, ok := new(big.Int).SetString(v, 10)
+		if !ok || maxSrcAmount.Sign() <= 0 {
+			invalid("MAX_SRC_AMOUNT", "a positive integer", v)
+		} else {
+			cfg.MaxSrcAmount = maxSrcAmount
+		}
+	}
+
+	if v := os.Getenv("SWAP_GAS_LIMIT"); v != "" {
+		gasLimit, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || gasLimit == 0 {
+			invalid("SWAP_GAS_LIMIT", "a positive integer", v)
+		}
+		cfg.SwapGasLimit = gasLimit
+	}
+
+	if v := os.Getenv("PRIORITY_FEE_WEI"); v != "" {
+		priorityFee, ok := new(big.Int).SetString(v, 10)
+		if !ok || priorityFee.Sign() < 0 {
+			invalid("PRIORITY_FEE_WEI", "a non-negative integer", v)
+		} else {
+			cfg.PriorityFee = priorityFee
+		}
+	}
+
+	if v := os.Getenv("ALLOW_NODE_OVERRIDE"); v != "" {
+		allow, err := strconv.ParseBool(v)
+		if err != nil {
+			invalid("ALLOW_NODE_OVERRIDE", "true or false", v)
+		}
+		cfg.AllowNodeOverride = allow
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return cfg, nil
+}
+
+// parseFactoryFees parses FACTORY_FEES, a comma-separated list of
+// factory:bps pairs. Factories are checked like request addresses, so a
+// mistyped checksum is rejected rather than silently keyed to another address.
+func parseFactoryFees(v string) (map[common.Address]SwapFee, error) {
+	fees := make(map[common.Address]SwapFee)
+	for _, entry := range strings.Split(v, ",") {
+		factoryStr, bpsStr, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid factory fee %q", entry)
+		}
+		factory, err := parseAddress("FACTORY_FEES", factoryStr)
+		if err != nil {
+			return nil, err
+		}
+		feeBps, err := strconv.ParseInt(bpsStr, 10, 64)
+		// A 100% fee would leave nothing to quote
+		if err != nil || feeBps < 0 || feeBps >= 10000 {
+			return nil, fmt.Errorf("invalid fee in %q", entry)
+		}
+		fees[factory] = SwapFeeFromBps(feeBps)
+	}
+	return fees, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestParseFactoryFees(t *testing.T) {
+	sushi := common.HexToAddress("0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2Ac")
+
+	fees, err := parseFactoryFees("0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2Ac:25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fees[sushi], SwapFeeFromBps(25); got != want {
+		t.Errorf("fee = %+v, want %+v", got, want)
+	}
+
+	// All-lowercase and all-uppercase addresses carry no checksum
+	for _, v := range []string{
+		"0xc0aee478e3658e2610c5f7a4a2e1777ce9e4f2ac:25",
+		"0xC0AEE478E3658E2610C5F7A4A2E1777CE9E4F2AC:25",
+	} {
+		fees, err := parseFactoryFees(v)
+		if err != nil {
+			t.Errorf("parseFactoryFees(%q): %v", v, err)
+			continue
+		}
+		if _, ok := fees[sushi]; !ok {
+			t.Errorf("parseFactoryFees(%q) = %v, want an entry for %s", v, fees, sushi.Hex())
+		}
+	}
+
+	for _, v := range []string{
+		"0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2Ac:10000",
+		"0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2Ac:-1",
+		"0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2Ac",
+		// Bad EIP-55 checksum: the last letter's case is flipped
+		"0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2AC:25",
+		"C0AEe478e3658e2610c5F7A4A2E1777cE9e4f2Ac:25",
+		"0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2:25",
+	} {
+		if _, err := parseFactoryFees(v); err == nil {
+			t.Errorf("parseFactoryFees(%q) succeeded, want an error", v)
+		}
+	}
+}
+
+func TestLoadConfigRejectsFullDefaultFee(t *testing.T) {
+	t.Setenv("ETH_NODE_URL", "http://localhost:8545")
+	t.Setenv("DEFAULT_FEE_BPS", "9999")
+	if _, err := LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig rejected DEFAULT_FEE_BPS=9999: %v", err)
+	}
+
+	t.Setenv("DEFAULT_FEE_BPS", "10000")
+	if _, err := LoadConfig(); err == nil {
+		t.Fatal("LoadConfig accepted DEFAULT_FEE_BPS=10000")
+	}
+}
+
+func TestLoadConfigParsesAddressesStrictly(t *testing.T) {
+	t.Setenv("ETH_NODE_URL", "http://localhost:8545")
+
+	for _, v := range []string{
+		"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2",
+		"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2",
+	} {
+		t.Setenv("WETH_ADDRESS", v)
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Errorf("LoadConfig rejected WETH_ADDRESS=%s: %v", v, err)
+			continue
+		}
+		if cfg.WETH != common.HexToAddress(v) {
+			t.Errorf("WETH = %s, want %s", cfg.WETH.Hex(), v)
+		}
+	}
+
+	for _, v := range []string{
+		// Bad EIP-55 checksum: the last letter's case is flipped
+		"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756CC2",
+		"C02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2",
+	} {
+		t.Setenv("WETH_ADDRESS", v)
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("LoadConfig accepted WETH_ADDRESS=%s", v)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"math/big"
+)
+
+const (
+	curveConstantProduct = "constant_product"
+	curveStableSwap      = "stableswap"
+)
+
+// defaultAmplification is the StableSwap A used when a request doesn't pass
+// amplification; Curve's stablecoin pools mostly run between 100 and 2000.
+const defaultAmplification = 100
+
+const maxAmplification = 1000000
+
+// stableSwapIterations caps the Newton iterations, as Curve's contracts do.
+const stableSwapIterations = 255
+
+// CurveReserves are a pool's reserves ordered for a swap from src to dst.
+type CurveReserves struct {
+	In  *big.Int
+	Out *big.Int
+}
+
+// CurveParams are the per-request settings a curve may use.
+type CurveParams struct {
+	Fee SwapFee
+	// Amplification is StableSwap's A; other curves ignore it
+	Amplification int64
+}
+
+// PriceCurve prices a swap against a pool's reserves. The fee is taken from
+// the input before the curve is applied, so fee_amount is the same for every
+// curve.
+type PriceCurve interface {
+	ComputeOut(amountIn *big.Int, reserves CurveReserves, params CurveParams) *big.Int
+}
+
+var curves = map[string]PriceCurve{
+	curveConstantProduct: constantProductCurve{},
+	curveStableSwap:      stableSwapCurve{},
+}
+
+// constantProductCurve is Uniswap V2's x*y=k.
+type constantProductCurve struct{}
+
+func (constantProductCurve) ComputeOut(amountIn *big.Int, reserves CurveReserves, params CurveParams) *big.Int {
+	return calculateSwapAmount(amountIn, reserves.In, reserves.Out, params.Fee)
+}
+
+// stableSwapCurve is Curve's two-coin StableSwap invariant,
+//
+//	A·n²·(x+y) + D = A·D·n² + D³/(n²·x·y)
+//
+// with n = 2. It treats one base unit of each token as equal in value, so it
+// only suits pegged pairs whose tokens have the same decimals.
+type stableSwapCurve struct{}
+
+func (stableSwapCurve) ComputeOut(amountIn *big.Int, reserves CurveReserves, params CurveParams) *big.Int {
+	if reserves.In.Sign() <= 0 || reserves.Out.Sign() <= 0 {
+		return new(big.Int)
+	}
+
+	ann := big.NewInt(params.Amplification * 4)
+	d := stableSwapD(reserves.In, reserves.Out, ann)
+
+	amountInWithFee := new(big.Int).Mul(amountIn, big.NewInt(params.Fee.Numerator))
+	amountInWithFee.Quo(amountInWithFee, big.NewInt(params.Fee.Denominator))
+
+	newReserveOut := stableSwapY(new(big.Int).Add(reserves.In, amountInWithFee), d, ann)
+
+	// Less one base unit so rounding in the iteration never overpays, as in
+	// Curve's get_dy
+	amountOut := new(big.Int).Sub(reserves.Out, newReserveOut)
+	amountOut.Sub(amountOut, big.NewInt(1))
+	if amountOut.Sign() < 0 {
+		return new(big.Int)
+	}
+	return amountOut
+}
+
+// stableSwapD solves the invariant for D given both reserves, by Newton's
+// method starting from D = x + y.
+func stableSwapD(x, y, ann *big.Int) *big.Int {
+	sum := new(big.Int).Add(x, y)
+	d := new(big.Int).Set(sum)
+	two, three := big.NewInt(2), big.NewInt(3)
+	annSum := new(big.Int).Mul(ann, sum)
+
+	for i := 0; i < stableSwapIterations; i++ {
+		// dP = D³ / (4xy), computed a factor at a time as Curve does
+		dP := new(big.Int).Set(d)
+		dP.Mul(dP, d).Quo(dP, new(big.Int).Mul(x, two))
+		dP.Mul(dP, d).Quo(dP, new(big.Int).Mul(y, two))
+
+		prev := d
+		// D = (Ann·S + 2·dP)·D / ((Ann−1)·D + 3·dP)
+		numerator := new(big.Int).Add(annSum, new(big.Int).Mul(dP, two))
+		numerator.Mul(numerator, d)
+		denominator := new(big.Int).Sub(ann, big.NewInt(1))
+		denominator.Mul(denominator, d)
+		denominator.Add(denominator, new(big.Int).Mul(dP, three))
+		d = numerator.Quo(numerator, denominator)
+
+		if new(big.Int).Sub(d, prev).CmpAbs(big.NewInt(1)) <= 0 {
+			break
+		}
+	}
+	return d
+}
+
+// stableSwapY solves the invariant for the other reserve once one reserve
+// is x, keeping D fixed.
+func stableSwapY(x, d, ann *big.Int) *big.Int {
+	two := big.NewInt(2)
+
+	// c = D³ / (4x·Ann), b = x + D/Ann
+	c := new(big.Int).Set(d)
+	c.Mul(c, d).Quo(c, new(big.Int).Mul(x, two))
+	c.Mul(c, d).Quo(c, new(big.Int).Mul(ann, two))
+	b := new(big.Int).Add(x, new(big.Int).Quo(d, ann))
+
+	y := new(big.Int).Set(d)
+	for i := 0; i < stableSwapIterations; i++ {
+		prev := y
+		// y = (y² + c) / (2y + b − D)
+		numerator := new(big.Int).Mul(y, y)
+		numerator.Add(numerator, c)
+		denominator := new(big.Int).Mul(y, two)
+		denominator.Add(denominator, b)
+		denominator.Sub(denominator, d)
+		y = numerator.Quo(numerator, denominator)
+
+		if new(big.Int).Sub(y, prev).CmpAbs(big.NewInt(1)) <= 0 {
+			break
+		}
+	}
+	return y
+}
+
+// curvePriceImpact measures price impact against the curve's own spot price.
+// Constant product has it in closed form; for other curves the spot price is
+// taken from a fee-free swap of a millionth of the input reserve.
+func curvePriceImpact(curve PriceCurve, amountIn, amountOut *big.Int, reserves CurveReserves, params CurveParams) *big.Rat {
+	if _, ok := curve.(constantProductCurve); ok {
+		return calculatePriceImpact(amountIn, amountOut, reserves.In, reserves.Out)
+	}
+
+	probeIn := new(big.Int).Quo(reserves.In, big.NewInt(1000000))
+	if probeIn.Sign() == 0 {
+		probeIn.SetInt64(1)
+	}
+	probeParams := params
+	probeParams.Fee = SwapFee{Numerator: 1, Denominator: 1}
+	probeOut := curve.ComputeOut(probeIn, reserves, probeParams)
+	if amountIn.Sign() == 0 || probeOut.Sign() == 0 {
+		return new(big.Rat)
+	}
+
+	// 1 - (amountOut/amountIn) / (probeOut/probeIn)
+	ratio := new(big.Rat).SetFrac(
+		new(big.Int).Mul(amountOut, probeIn),
+		new(big.Int).Mul(amountIn, probeOut),
+	)
+	impact := new(big.Rat).Sub(big.NewRat(1, 1), ratio)
+	return impact.Mul(impact, big.NewRat(100, 1))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestConstantProductCurveMatchesCalculateSwapAmount(t *testing.T) {
+	reserves := CurveReserves{In: bigInt(t, "100000000000000000000"), Out: bigInt(t, "200000000000000000000")}
+	params := CurveParams{Fee: DefaultSwapFee}
+
+	for _, in := range []string{"0", "1", "1000000000000000000", "500000000000000000000"} {
+		amountIn := bigInt(t, in)
+		got := constantProductCurve{}.ComputeOut(amountIn, reserves, params)
+		want := calculateSwapAmount(amountIn, reserves.In, reserves.Out, params.Fee)
+		if got.Cmp(want) != 0 {
+			t.Errorf("ComputeOut(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestStableSwapD(t *testing.T) {
+	ann := big.NewInt(100 * 4)
+
+	// A balanced pool's invariant is the sum of its reserves
+	x := bigInt(t, "1000000000000000000000000")
+	if got, want := stableSwapD(x, x, ann), bigInt(t, "2000000000000000000000000"); got.Cmp(want) != 0 {
+		t.Errorf("balanced D = %s, want %s", got, want)
+	}
+
+	// Otherwise it lies between the constant-sum and constant-product values
+	x, y := bigInt(t, "1500000000000000000000000"), bigInt(t, "500000000000000000000000")
+	d := stableSwapD(x, y, ann)
+	sum := new(big.Int).Add(x, y)
+	geometric := new(big.Int).Sqrt(new(big.Int).Mul(x, y))
+	geometric.Mul(geometric, big.NewInt(2))
+	if d.Cmp(sum) > 0 || d.Cmp(geometric) < 0 {
+		t.Errorf("D = %s, want between 2·sqrt(xy) %s and x+y %s", d, geometric, sum)
+	}
+
+	// stableSwapY inverts stableSwapD
+	if got := stableSwapY(x, d, ann); new(big.Int).Sub(got, y).CmpAbs(big.NewInt(2)) > 0 {
+		t.Errorf("stableSwapY(x, D) = %s, want %s within 2", got, y)
+	}
+}
+
+func TestStableSwapCurve(t *testing.T) {
+	million := bigInt(t, "1000000000000000000000000")
+	balanced := CurveReserves{In: million, Out: million}
+	amountIn := bigInt(t, "10000000000000000000000")
+
+	noFee := CurveParams{Fee: SwapFeeFromBps(0), Amplification: defaultAmplification}
+	out := stableSwapCurve{}.ComputeOut(amountIn, balanced, noFee)
+
+	// A 1% trade in a balanced pool stays within 0.01% of one-for-one
+	minOut := new(big.Int).Mul(amountIn, big.NewInt(9999))
+	minOut.Quo(minOut, big.NewInt(10000))
+	if out.Cmp(amountIn) >= 0 || out.Cmp(minOut) < 0 {
+		t.Errorf("ComputeOut = %s, want just under %s", out, amountIn)
+	}
+
+	// ... which is far better than constant product
+	if cp := calculateSwapAmount(amountIn, million, million, noFee.Fee); out.Cmp(cp) <= 0 {
+		t.Errorf("StableSwap out %s isn't better than constant product %s", out, cp)
+	}
+
+	// Higher amplification flattens the curve further
+	flatter := stableSwapCurve{}.ComputeOut(amountIn, balanced, CurveParams{Fee: noFee.Fee, Amplification: 2000})
+	if flatter.Cmp(out) <= 0 {
+		t.Errorf("A=2000 out %s isn't better than A=%d out %s", flatter, defaultAmplification, out)
+	}
+
+	// The fee comes off the input first
+	withFee := stableSwapCurve{}.ComputeOut(amountIn, balanced, CurveParams{Fee: DefaultSwapFee, Amplification: defaultAmplification})
+	feeIn := new(big.Int).Mul(amountIn, big.NewInt(997))
+	feeIn.Quo(feeIn, big.NewInt(1000))
+	if want := (stableSwapCurve{}).ComputeOut(feeIn, balanced, noFee); withFee.Cmp(want) != 0 {
+		t.Errorf("ComputeOut with fee = %s, want %s", withFee, want)
+	}
+
+	// Draining most of one side gets expensive even on a flat curve
+	huge := stableSwapCurve{}.ComputeOut(new(big.Int).Mul(million, big.NewInt(10)), balanced, noFee)
+	if huge.Cmp(million) >= 0 {
+		t.Errorf("ComputeOut of 10x the reserve = %s, want below the reserve %s", huge, million)
+	}
+}
+
+func TestStableSwapCurveEdgeCases(t *testing.T) {
+	params := CurveParams{Fee: DefaultSwapFee, Amplification: defaultAmplification}
+	million := bigInt(t, "1000000000000000000000000")
+
+	for _, tt := range []struct {
+		name     string
+		amountIn *big.Int
+		reserves CurveReserves
+	}{
+		{"zero input", new(big.Int), CurveReserves{In: million, Out: million}},
+		{"empty reserve in", bigInt(t, "1000"), CurveReserves{In: new(big.Int), Out: million}},
+		{"empty reserve out", bigInt(t, "1000"), CurveReserves{In: million, Out: new(big.Int)}},
+	} {
+		if got := (stableSwapCurve{}).ComputeOut(tt.amountIn, tt.reserves, params); got.Sign() != 0 {
+			t.Errorf("%s: ComputeOut = %s, want 0", tt.name, got)
+		}
+	}
+}
+
+func TestCurvePriceImpact(t *testing.T) {
+	million := bigInt(t, "1000000000000000000000000")
+	reserves := CurveReserves{In: million, Out: million}
+	params := CurveParams{Fee: SwapFeeFromBps(0), Amplification: defaultAmplification}
+	amountIn := bigInt(t, "10000000000000000000000")
+
+	stableOut := stableSwapCurve{}.ComputeOut(amountIn, reserves, params)
+	stableImpact := curvePriceImpact(stableSwapCurve{}, amountIn, stableOut, reserves, params)
+
+	cpOut := constantProductCurve{}.ComputeOut(amountIn, reserves, params)
+	cpImpact := curvePriceImpact(constantProductCurve{}, amountIn, cpOut, reserves, params)
+
+	if stableImpact.Sign() < 0 || stableImpact.Cmp(cpImpact) >= 0 {
+		t.Errorf("StableSwap impact %s%%, want non-negative and below constant product's %s%%", stableImpact.FloatString(6), cpImpact.FloatString(6))
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var ErrPoolDenied = errors.New("pool denied")
+
+// poolDenylist holds the pools from POOL_DENYLIST. When it was read from a
+// file, reload picks up edits to the file without a restart.
+type poolDenylist struct {
+	source string
+	pools  atomic.Pointer[map[common.Address]bool]
+}
+
+// newPoolDenylist reads POOL_DENYLIST, which uses the same format as
+// POOL_ALLOWLIST. Unlike the allowlist it may be empty, so a file can be
+// emptied to unblock every pool.
+func newPoolDenylist(source string) (*poolDenylist, error) {
+	dl := &poolDenylist{source: source}
+	if err := dl.reload(); err != nil {
+		return nil, err
+	}
+	return dl, nil
+}
+
+// reload re-reads the denylist. On error the previous list stays in effect.
+func (dl *poolDenylist) reload() error {
+	pools, err := readPoolList(dl.source)
+	if err != nil {
+		return fmt.Errorf("failed to load pool denylist: %w", err)
+	}
+	dl.pools.Store(&pools)
+	return nil
+}
+
+// contains is safe to call on a nil denylist.
+func (dl *poolDenylist) contains(pool common.Address) bool {
+	if dl == nil {
+		return false
+	}
+	return (*dl.pools.Load())[pool]
+}
+
+func (dl *poolDenylist) len() int {
+	if dl == nil {
+		return 0
+	}
+	return len(*dl.pools.Load())
+}
+
+// SetPoolDenylist rejects the given pools with 403, even when they are on
+// the allowlist. A nil denylist rejects nothing.
+func (se *SwapEstimator) SetPoolDenylist(denylist *poolDenylist) {
+	se.poolDenylist = denylist
+}
+
+// ReloadPoolDenylist re-reads the denylist and drops cached responses, so
+// newly denied pools stop being served immediately. It returns the number
+// of denied pools.
+func (se *SwapEstimator) ReloadPoolDenylist() (int, error) {
+	if se.poolDenylist == nil {
+		return 0, nil
+	}
+	if err := se.poolDenylist.reload(); err != nil {
+		return se.poolDenylist.len(), err
+	}
+	se.responseCache.clear()
+	return se.poolDenylist.len(), nil
+}
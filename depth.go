@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	defaultDepthPoints = 10
+	maxDepthPoints     = 100
+)
+
+const (
+	depthScaleLinear = "linear"
+	depthScaleLog    = "log"
+)
+
+type DepthPoint struct {
+	SrcAmount string `json:"src_amount"`
+	DstAmount string `json:"dst_amount"`
+	// Price is the execution price in whole dst tokens per whole src token
+	Price       string `json:"price"`
+	PriceImpact string `json:"price_impact"`
+}
+
+type DepthResponse struct {
+	SpotPrice string `json:"spot_price"`
+	// Points run from src_amount down to the smallest size
+	Points []DepthPoint `json:"points"`
+}
+
+// depthAmounts returns the input sizes for a depth chart, largest first. A
+// linear scale steps down by maxAmount/points each time; a log scale halves
+// the amount at each step and stops before it reaches zero.
+func depthAmounts(maxAmount *big.Int, points int, scale string) []*big.Int {
+	amounts := make([]*big.Int, 0, points)
+	for i := 0; i < points; i++ {
+		var amount *big.Int
+		if scale == depthScaleLog {
+			amount = new(big.Int).Rsh(maxAmount, uint(i))
+		} else {
+			amount = new(big.Int).Mul(maxAmount, big.NewInt(int64(points-i)))
+			amount.Quo(amount, big.NewInt(int64(points)))
+		}
+		if amount.Sign() == 0 {
+			break
+		}
+		amounts = append(amounts, amount)
+	}
+	return amounts
+}
+
+// EstimateDepth quotes every size from depthAmounts against a single read of
+// the pool's reserves, so the points describe one consistent state.
+func (se *SwapEstimator) EstimateDepth(ctx context.Context, poolAddr, srcToken, dstToken common.Address, maxAmount *big.Int, points int, scale string, blockNumber *big.Int) (*DepthResponse, error) {
+	reserves, err := se.getDirectionalReserves(ctx, poolAddr, srcToken, dstToken, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	srcDecimals, dstDecimals, err := se.ethClient.GetPairDecimals(ctx, srcToken, dstToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch decimals: %w", err)
+	}
+
+	response := &DepthResponse{
+		SpotPrice: formatRat(normalizedRatio(reserves.ReserveOut, reserves.ReserveIn, dstDecimals, srcDecimals), priceDisplayPrecision),
+	}
+	for _, amountIn := range depthAmounts(maxAmount, points, scale) {
+		amountOut := calculateSwapAmount(amountIn, reserves.ReserveIn, reserves.ReserveOut, se.fee)
+		if err := checkAmountOut(amountOut, reserves.ReserveOut); err != nil {
+			return nil, fmt.Errorf("pool %s: %w", poolAddr.Hex(), err)
+		}
+
+		response.Points = append(response.Points, DepthPoint{
+			SrcAmount:   amountIn.String(),
+			DstAmount:   amountOut.String(),
+			Price:       formatRat(normalizedRatio(amountOut, amountIn, dstDecimals, srcDecimals), priceDisplayPrecision),
+			PriceImpact: calculatePriceImpact(amountIn, amountOut, reserves.ReserveIn, reserves.ReserveOut).FloatString(4),
+		})
+	}
+
+	return response, nil
+}
+
+func (se *SwapEstimator) depthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	poolStr := query.Get("pool")
+	srcStr := query.Get("src")
+	dstStr := query.Get("dst")
+	srcAmountStr := query.Get("src_amount")
+
+	if poolStr == "" || srcStr == "" || dstStr == "" || srcAmountStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameters: pool, src, dst, src_amount")
+		return
+	}
+
+	poolAddr, err := parseAddress("pool", poolStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	points := defaultDepthPoints
+	if v := query.Get("points"); v != "" {
+		points, err = strconv.Atoi(v)
+		if err != nil || points < 1 || points > maxDepthPoints {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("Invalid points: must be an integer between 1 and %d", maxDepthPoints))
+			return
+		}
+	}
+
+	scale := query.Get("scale")
+	if scale == "" {
+		scale = depthScaleLinear
+	}
+	if scale != depthScaleLinear && scale != depthScaleLog {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "Invalid scale: must be linear or log")
+		return
+	}
+
+	blockNumber, err := parseBlockParams(query.Get("block"), query.Get("block_tag"))
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	se, err = se.forChain(query.Get("chain_id"))
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if !errors.Is(err, ErrChainNotConfigured) {
+			slog.ErrorContext(r.Context(), "depth estimate failed", "pool", poolStr, "error", err)
+			status, message = http.StatusInternalServerError, "Failed to connect to chain"
+		}
+		writeError(w, status, errorCode(status, err), message)
+		return
+	}
+
+	tokens, err := se.resolveSwapTokens(srcStr, dstStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	srcAmount, err := se.parseSrcAmount(srcAmountStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	response, err := se.EstimateDepth(ctx, poolAddr, tokens.Src, tokens.Dst, srcAmount, points, scale, blockNumber)
+	if err != nil {
+		slog.WarnContext(ctx, "depth estimate failed", "pool", poolStr, "src", srcStr, "dst", dstStr, "src_amount", srcAmountStr, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestDepthAmounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		max    string
+		points int
+		scale  string
+		want   []string
+	}{
+		{"linear", "10000", 4, depthScaleLinear, []string{"10000", "7500", "5000", "2500"}},
+		{"linear truncates", "10", 3, depthScaleLinear, []string{"10", "6", "3"}},
+		{"log halves", "1000", 4, depthScaleLog, []string{"1000", "500", "250", "125"}},
+		{"log stops at zero", "5", 10, depthScaleLog, []string{"5", "2", "1"}},
+		{"linear stops at zero", "2", 5, depthScaleLinear, []string{"2", "1", "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := depthAmounts(bigInt(t, tt.max), tt.points, tt.scale)
+			if len(got) != len(tt.want) {
+				t.Fatalf("depthAmounts = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].String() != tt.want[i] {
+					t.Errorf("depthAmounts = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestEstimateDepth(t *testing.T) {
+	reserves := testReserves(t)
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: reserves},
+	})
+	chain.decimals = map[common.Address]uint8{testToken0: 18, testToken1: 18}
+	se := NewSwapEstimator(chain)
+
+	depth, err := se.EstimateDepth(context.Background(), testPool, testToken0, testToken1, bigInt(t, "10000000000000000000"), 5, depthScaleLinear, nil)
+	if err != nil {
+		t.Fatalf("EstimateDepth: %v", err)
+	}
+
+	if depth.SpotPrice != "2" {
+		t.Errorf("spot price = %s, want 2", depth.SpotPrice)
+	}
+	if len(depth.Points) != 5 {
+		t.Fatalf("got %d points, want 5", len(depth.Points))
+	}
+	// Every point comes from the one read of the reserves
+	if reads := chain.reserveReads.Load(); reads != 1 {
+		t.Errorf("read reserves %d times, want 1", reads)
+	}
+
+	wantSrc := []string{"10000000000000000000", "8000000000000000000", "6000000000000000000", "4000000000000000000", "2000000000000000000"}
+	var prevPrice *big.Rat
+	for i, p := range depth.Points {
+		if p.SrcAmount != wantSrc[i] {
+			t.Errorf("point %d src_amount = %s, want %s", i, p.SrcAmount, wantSrc[i])
+		}
+		want := calculateSwapAmount(bigInt(t, p.SrcAmount), reserves.Reserve0, reserves.Reserve1, DefaultSwapFee)
+		if p.DstAmount != want.String() {
+			t.Errorf("point %d dst_amount = %s, want %s", i, p.DstAmount, want)
+		}
+
+		// Smaller trades get a better price, still below the spot price
+		price, ok := new(big.Rat).SetString(p.Price)
+		if !ok {
+			t.Fatalf("point %d price %q isn't a number", i, p.Price)
+		}
+		if price.Cmp(big.NewRat(2, 1)) >= 0 {
+			t.Errorf("point %d price %s isn't below the spot price", i, p.Price)
+		}
+		if prevPrice != nil && price.Cmp(prevPrice) <= 0 {
+			t.Errorf("point %d price %s isn't better than the larger trade's %s", i, p.Price, prevPrice.FloatString(6))
+		}
+		prevPrice = price
+	}
+}
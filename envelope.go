@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ResponseMeta describes the state a quote was computed against.
+type ResponseMeta struct {
+	ChainID     uint64 `json:"chain_id"`
+	BlockNumber uint64 `json:"block_number"`
+	// Timestamp is when the quote was computed, in Unix seconds
+	Timestamp int64 `json:"timestamp"`
+}
+
+// ResponseEnvelope wraps a response when the request asks for envelope=true.
+type ResponseEnvelope struct {
+	Data any          `json:"data"`
+	Meta ResponseMeta `json:"meta"`
+}
+
+// GetBlockNumber resolves blockNumber, which may be a tag or nil for the
+// default block, to the number of the block it refers to right now.
+func (ec *EthereumClient) GetBlockNumber(ctx context.Context, blockNumber *big.Int) (uint64, error) {
+	header, err := ec.getHeader(ctx, ec.blockOrDefault(blockNumber))
+	if err != nil {
+		return 0, err
+	}
+	return header.Number.Uint64(), nil
+}
+
+// ChainID returns the node's chain ID, which is fetched once and cached.
+func (ec *EthereumClient) ChainID(ctx context.Context) (*big.Int, error) {
+	ec.chainIDMu.Lock()
+	defer ec.chainIDMu.Unlock()
+
+	if ec.chainID != nil {
+		return ec.chainID, nil
+	}
+
+	chainID, err := withRetry(ctx, ec.maxRetries, func() (*big.Int, error) {
+		start := time.Now()
+		client := ec.conn()
+		chainID, err := client.ChainID(ctx)
+		observeRPCCall("chainId", start, err)
+		ec.checkConnection(client, err)
+		return chainID, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to get chain ID: %w", ErrRPCFailure, err)
+	}
+
+	ec.chainID = chainID
+	return chainID, nil
+}
+
+// pinQuoteBlock resolves the block a quote will read, including tags and the
+// default of latest, and fixes opts.BlockNumber to it so every call made for
+// the quote reads the block reported in its metadata.
+func (se *SwapEstimator) pinQuoteBlock(ctx context.Context, opts *EstimateOptions) (*ResponseMeta, error) {
+	chainID, err := se.ethClient.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	blockNumber, err := se.ethClient.GetBlockNumber(ctx, opts.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	opts.BlockNumber = new(big.Int).SetUint64(blockNumber)
+
+	return &ResponseMeta{
+		ChainID:     chainID.Uint64(),
+		BlockNumber: blockNumber,
+	}, nil
+}
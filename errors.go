@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrPoolNotFound          = errors.New("pool not found")
+	ErrNotUniswapV2Pair      = errors.New("address is not a Uniswap V2 pair")
+	ErrTokenMismatch         = errors.New("tokens don't match pool")
+	ErrInsufficientLiquidity = errors.New("insufficient liquidity")
+	ErrNoLiquidity           = errors.New("pool has no liquidity")
+	ErrStateUnavailable      = errors.New("state not available at requested block")
+	ErrRPCFailure            = errors.New("ethereum node request failed")
+
+	errEmptyResult     = errors.New("empty call result")
+	errMalformedResult = errors.New("malformed call result")
+	// errImpossibleQuote means an estimate broke the constant-product
+	// invariant, which points to a bug or corrupted reserves
+	errImpossibleQuote = errors.New("impossible quote")
+)
+
+// Error codes returned in the code field of error responses, so clients can
+// branch on the kind of failure without parsing messages. They are part of
+// the API: add new ones rather than renaming.
+const (
+	CodeMissingParameter      = "MISSING_PARAMETER"
+	CodeInvalidParameter      = "INVALID_PARAMETER"
+	CodeInvalidAddress        = "INVALID_ADDRESS"
+	CodeInvalidAmount         = "INVALID_AMOUNT"
+	CodeSameToken             = "SAME_TOKEN"
+	CodeInvalidBody           = "INVALID_BODY"
+	CodeChainNotConfigured    = "CHAIN_NOT_CONFIGURED"
+	CodePoolNotFound          = "POOL_NOT_FOUND"
+	CodePoolNotAllowed        = "POOL_NOT_ALLOWED"
+	CodePoolDenied            = "POOL_DENIED"
+	CodeNotAPair              = "NOT_A_PAIR"
+	CodeTokenMismatch         = "TOKEN_MISMATCH"
+	CodeNoLiquidity           = "NO_LIQUIDITY"
+	CodeInsufficientLiquidity = "INSUFFICIENT_LIQUIDITY"
+	CodeStateUnavailable      = "STATE_UNAVAILABLE"
+	CodeMethodNotAllowed      = "METHOD_NOT_ALLOWED"
+	CodeRateLimited           = "RATE_LIMITED"
+	CodeRPCError              = "RPC_ERROR"
+	CodeNodeUnavailable       = "NODE_UNAVAILABLE"
+	CodeOverloaded            = "OVERLOADED"
+	CodeTimeout               = "TIMEOUT"
+	CodeInternalError         = "INTERNAL_ERROR"
+)
+
+var errorCodes = []string{
+	CodeMissingParameter, CodeInvalidParameter, CodeInvalidAddress, CodeInvalidAmount,
+	CodeSameToken, CodeInvalidBody, CodeChainNotConfigured, CodePoolNotFound, CodePoolNotAllowed,
+	CodePoolDenied, CodeNotAPair, CodeTokenMismatch, CodeNoLiquidity, CodeInsufficientLiquidity,
+	CodeStateUnavailable, CodeMethodNotAllowed, CodeRateLimited, CodeRPCError,
+	CodeNodeUnavailable, CodeOverloaded, CodeTimeout, CodeInternalError,
+}
+
+// codedError attaches an error code to a validation error whose kind can't be
+// told from a sentinel.
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+func withCode(code string, err error) error {
+	return &codedError{code: code, err: err}
+}
+
+// errorCode returns the code for err, falling back to one for the HTTP
+// status it is reported with.
+func errorCode(status int, err error) string {
+	var coded *codedError
+	switch {
+	case errors.As(err, &coded):
+		return coded.code
+	case errors.Is(err, ErrInvalidAddress):
+		return CodeInvalidAddress
+	case errors.Is(err, errSameToken):
+		return CodeSameToken
+	case errors.Is(err, ErrChainNotConfigured):
+		return CodeChainNotConfigured
+	case isTimeout(err):
+		return CodeTimeout
+	case errors.Is(err, ErrPoolNotFound):
+		return CodePoolNotFound
+	case errors.Is(err, ErrPoolNotAllowed):
+		return CodePoolNotAllowed
+	case errors.Is(err, ErrPoolDenied):
+		return CodePoolDenied
+	case errors.Is(err, ErrNotUniswapV2Pair):
+		return CodeNotAPair
+	case errors.Is(err, ErrTokenMismatch):
+		return CodeTokenMismatch
+	case errors.Is(err, ErrNoLiquidity):
+		return CodeNoLiquidity
+	case errors.Is(err, ErrInsufficientLiquidity):
+		return CodeInsufficientLiquidity
+	case errors.Is(err, ErrStateUnavailable):
+		return CodeStateUnavailable
+	case errors.Is(err, ErrNodeUnavailable):
+		return CodeNodeUnavailable
+	case errors.Is(err, ErrRPCFailure):
+		return CodeRPCError
+	}
+
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidParameter
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway:
+		return CodeRPCError
+	case http.StatusServiceUnavailable:
+		return CodeNodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	default:
+		return CodeInternalError
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message, Code: code})
+}
+
+// writeRequestError reports err, typically a validation error, as is.
+func writeRequestError(w http.ResponseWriter, status int, err error) {
+	writeError(w, status, errorCode(status, err), err.Error())
+}
+
+func isTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// estimateErrorStatus maps estimator errors to the HTTP status returned to
+// the client. Anything unrecognised is treated as an internal error.
+func estimateErrorStatus(err error) int {
+	switch {
+	case isTimeout(err):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, ErrPoolNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrPoolNotAllowed), errors.Is(err, ErrPoolDenied):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNotUniswapV2Pair),
+		errors.Is(err, ErrTokenMismatch),
+		errors.Is(err, ErrInsufficientLiquidity),
+		errors.Is(err, ErrNoLiquidity),
+		errors.Is(err, ErrStateUnavailable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrNodeUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrRPCFailure):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeEstimateError(w http.ResponseWriter, err error) {
+	status := estimateErrorStatus(err)
+	writeError(w, status, errorCode(status, err), estimateErrorMessage(err))
+}
+
+// estimateErrorMessage is the message clients see for an estimate error.
+// Server and node errors are replaced with a generic message, since their
+// text can carry raw RPC responses and internal details.
+func estimateErrorMessage(err error) string {
+	switch {
+	case isTimeout(err):
+		return "Timed out waiting for the Ethereum node"
+	case estimateErrorStatus(err) >= http.StatusInternalServerError:
+		return "Failed to estimate swap"
+	default:
+		return err.Error()
+	}
+}
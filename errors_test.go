@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestEstimateErrorMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"node error", fmt.Errorf("%w: dial tcp 10.0.0.1:8545: connection refused", ErrRPCFailure), "Failed to estimate swap"},
+		{"malformed result", fmt.Errorf("getReserves: %w: 40 bytes", errMalformedResult), "Failed to estimate swap"},
+		{"timeout", fmt.Errorf("%w: failed to get reserves: %w", ErrRPCFailure, context.DeadlineExceeded), "Timed out waiting for the Ethereum node"},
+		{"client error", fmt.Errorf("%w: requested output exceeds available reserve", ErrInsufficientLiquidity), "insufficient liquidity: requested output exceeds available reserve"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateErrorMessage(tt.err); got != tt.want {
+				t.Errorf("estimateErrorMessage = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
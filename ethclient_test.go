@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// mockNode is a JSON-RPC node for tests. It answers eth_call with canned
+// replies keyed by contract address and method selector; calls without a
+// reply return no data, as a node does for addresses without code.
+// eth_getStorageAt reads slots set with setStorage, and zero otherwise.
+type mockNode struct {
+	t   testing.TB
+	url string
+
+	mu      sync.Mutex
+	replies map[mockCall]mockReply
+	storage map[mockSlot]common.Hash
+}
+
+type mockSlot struct {
+	contract common.Address
+	slot     common.Hash
+}
+
+type mockCall struct {
+	to       common.Address
+	selector string
+}
+
+// mockReply is what the node returns for one call. Only one field is set.
+type mockReply struct {
+	// result is the call's ABI-encoded return data
+	result []byte
+	// revert answers with an "execution reverted" error
+	revert bool
+	// raw is sent as the JSON-RPC result verbatim
+	raw json.RawMessage
+}
+
+type mockRPCRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type mockRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *mockRPCError   `json:"error,omitempty"`
+}
+
+type mockRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+func newMockNode(t testing.TB) *mockNode {
+	t.Helper()
+
+	m := &mockNode{t: t, replies: make(map[mockCall]mockReply), storage: make(map[mockSlot]common.Hash)}
+	srv := httptest.NewServer(http.HandlerFunc(m.serveHTTP))
+	t.Cleanup(srv.Close)
+	m.url = srv.URL
+	return m
+}
+
+// reply makes calls of method on to answer with r.
+func (m *mockNode) reply(to common.Address, method abi.Method, r mockReply) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replies[mockCall{to: to, selector: hexutil.Encode(method.ID)}] = r
+}
+
+func (m *mockNode) setStorage(contract common.Address, slot, value common.Hash) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.storage[mockSlot{contract: contract, slot: slot}] = value
+}
+
+// client returns an EthereumClient for the node that doesn't retry, so
+// error paths take one round trip.
+func (m *mockNode) client() *EthereumClient {
+	m.t.Helper()
+
+	ec, err := NewEthereumClient(m.url)
+	if err != nil {
+		m.t.Fatalf("NewEthereumClient: %v", err)
+	}
+	ec.SetMaxRetries(0)
+	m.t.Cleanup(ec.Close)
+	return ec
+}
+
+func (m *mockNode) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// The client batches some requests
+	if strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+		var reqs []mockRPCRequest
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resps := make([]mockRPCResponse, len(reqs))
+		for i, req := range reqs {
+			resps[i] = m.handle(req)
+		}
+		json.NewEncoder(w).Encode(resps)
+		return
+	}
+
+	var req mockRPCRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(m.handle(req))
+}
+
+func (m *mockNode) handle(req mockRPCRequest) mockRPCResponse {
+	resp := mockRPCResponse{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == "eth_getStorageAt" && len(req.Params) >= 2 {
+		var contract common.Address
+		var slot common.Hash
+		if err := json.Unmarshal(req.Params[0], &contract); err != nil {
+			resp.Error = &mockRPCError{Code: -32602, Message: err.Error()}
+			return resp
+		}
+		if err := json.Unmarshal(req.Params[1], &slot); err != nil {
+			resp.Error = &mockRPCError{Code: -32602, Message: err.Error()}
+			return resp
+		}
+		m.mu.Lock()
+		value := m.storage[mockSlot{contract: contract, slot: slot}]
+		m.mu.Unlock()
+		resp.Result, _ = json.Marshal(value)
+		return resp
+	}
+	if req.Method != "eth_call" || len(req.Params) == 0 {
+		resp.Error = &mockRPCError{Code: -32601, Message: "method not supported by mock node: " + req.Method}
+		return resp
+	}
+
+	var call struct {
+		To    common.Address `json:"to"`
+		Input hexutil.Bytes  `json:"input"`
+		Data  hexutil.Bytes  `json:"data"`
+	}
+	if err := json.Unmarshal(req.Params[0], &call); err != nil {
+		resp.Error = &mockRPCError{Code: -32602, Message: err.Error()}
+		return resp
+	}
+	input := call.Input
+	if len(input) == 0 {
+		input = call.Data
+	}
+	if len(input) < 4 {
+		resp.Result = json.RawMessage(`"0x"`)
+		return resp
+	}
+
+	m.mu.Lock()
+	r, ok := m.replies[mockCall{to: call.To, selector: hexutil.Encode(input[:4])}]
+	m.mu.Unlock()
+
+	switch {
+	case !ok:
+		resp.Result = json.RawMessage(`"0x"`)
+	case r.revert:
+		resp.Error = &mockRPCError{Code: 3, Message: "execution reverted", Data: "0x"}
+	case r.raw != nil:
+		resp.Result = r.raw
+	default:
+		resp.Result, _ = json.Marshal(hexutil.Encode(r.result))
+	}
+	return resp
+}
+
+// mockPair serves token0, token1 and getReserves for a pair at pool.
+func mockPair(t testing.TB, m *mockNode, ec *EthereumClient, pool, token0, token1 common.Address, reserve0, reserve1 *big.Int, blockTimestampLast uint32) {
+	t.Helper()
+
+	for method, token := range map[string]common.Address{"token0": token0, "token1": token1} {
+		result, err := ec.abi.Methods[method].Outputs.Pack(token)
+		if err != nil {
+			t.Fatalf("pack %s: %v", method, err)
+		}
+		m.reply(pool, ec.abi.Methods[method], mockReply{result: result})
+	}
+
+	result, err := ec.abi.Methods["getReserves"].Outputs.Pack(reserve0, reserve1, blockTimestampLast)
+	if err != nil {
+		t.Fatalf("pack getReserves: %v", err)
+	}
+	m.reply(pool, ec.abi.Methods["getReserves"], mockReply{result: result})
+}
+
+func TestEthereumClientEstimateSwap(t *testing.T) {
+	node := newMockNode(t)
+	ec := node.client()
+	reserves := testReserves(t)
+	mockPair(t, node, ec, testPool, testToken0, testToken1, reserves.Reserve0, reserves.Reserve1, reserves.BlockTimestampLast)
+
+	se := NewSwapEstimator(ec)
+	est, err := se.EstimateSwap(context.Background(), testPool, testToken0, testToken1, bigInt(t, "1000000000000000000"))
+	if err != nil {
+		t.Fatalf("EstimateSwap: %v", err)
+	}
+
+	if got, want := est.AmountOut.String(), "1974316068794122597"; got != want {
+		t.Errorf("AmountOut = %s, want %s", got, want)
+	}
+	if est.ReserveIn.Cmp(reserves.Reserve0) != 0 || est.ReserveOut.Cmp(reserves.Reserve1) != 0 {
+		t.Errorf("reserves = %s/%s, want %s/%s", est.ReserveIn, est.ReserveOut, reserves.Reserve0, reserves.Reserve1)
+	}
+	if est.BlockTimestampLast != reserves.BlockTimestampLast {
+		t.Errorf("BlockTimestampLast = %d, want %d", est.BlockTimestampLast, reserves.BlockTimestampLast)
+	}
+}
+
+func TestEthereumClientEstimateSwapErrors(t *testing.T) {
+	reserves := testReserves(t)
+
+	tests := []struct {
+		name string
+		// getReserves replaces the pair's getReserves reply
+		getReserves mockReply
+		wantErr     error
+	}{
+		{"reverted call", mockReply{revert: true}, ErrNotUniswapV2Pair},
+		{"truncated return data", mockReply{result: make([]byte, 40)}, ErrNotUniswapV2Pair},
+		{"malformed JSON-RPC result", mockReply{raw: json.RawMessage(`"not hex"`)}, ErrRPCFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := newMockNode(t)
+			ec := node.client()
+			mockPair(t, node, ec, testPool, testToken0, testToken1, reserves.Reserve0, reserves.Reserve1, reserves.BlockTimestampLast)
+			node.reply(testPool, ec.abi.Methods["getReserves"], tt.getReserves)
+
+			se := NewSwapEstimator(ec)
+			_, err := se.EstimateSwap(context.Background(), testPool, testToken0, testToken1, bigInt(t, "1000000000000000000"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEthereumClientEstimateSwapNoContract(t *testing.T) {
+	node := newMockNode(t)
+	se := NewSwapEstimator(node.client())
+
+	_, err := se.EstimateSwap(context.Background(), testPool, testToken0, testToken1, bigInt(t, "1000000000000000000"))
+	if !errors.Is(err, ErrPoolNotFound) {
+		t.Fatalf("err = %v, want %v", err, ErrPoolNotFound)
+	}
+}
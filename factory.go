@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Uniswap V2 factory on Ethereum mainnet
+var defaultFactoryAddress = common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
+
+func (ec *EthereumClient) GetPair(ctx context.Context, factoryAddr, tokenA, tokenB common.Address) (common.Address, error) {
+	data, err := ec.factoryABI.Pack("getPair", tokenA, tokenB)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to pack getPair call: %w", err)
+	}
+
+	result, err := ec.callContract(ctx, "getPair", factoryAddr, data, nil)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to call getPair: %w", err)
+	}
+
+	unpacked, err := ec.factoryABI.Unpack("getPair", result)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: failed to unpack getPair result: %w", errMalformedResult, err)
+	}
+
+	if len(unpacked) == 0 {
+		return common.Address{}, fmt.Errorf("empty getPair result")
+	}
+
+	pairAddr, ok := unpacked[0].(common.Address)
+	if !ok {
+		return common.Address{}, fmt.Errorf("failed to cast getPair to common.Address")
+	}
+
+	return pairAddr, nil
+}
+
+func (se *SwapEstimator) SetFactory(factoryAddr common.Address) {
+	se.factory = factoryAddr
+}
+
+// SetFactoryFees sets the LP fee charged by pools from each factory, for
+// forks that don't charge Uniswap's 0.3%.
+func (se *SwapEstimator) SetFactoryFees(fees map[common.Address]SwapFee) {
+	se.factoryFees = fees
+}
+
+// feeForFactory returns the LP fee for pools created by factoryAddr, falling
+// back to the default fee for factories without one configured.
+func (se *SwapEstimator) feeForFactory(factoryAddr common.Address) SwapFee {
+	if fee, ok := se.factoryFees[factoryAddr]; ok {
+		return fee
+	}
+	return se.fee
+}
+
+// ResolvePool looks up the pair for srcToken/dstToken on factoryAddr.
+func (se *SwapEstimator) ResolvePool(ctx context.Context, factoryAddr, srcToken, dstToken common.Address) (common.Address, error) {
+	poolAddr, err := se.ethClient.GetPair(ctx, factoryAddr, srcToken, dstToken)
+	if err != nil {
+		if isTimeout(err) {
+			return common.Address{}, fmt.Errorf("failed to resolve pool: %w", err)
+		}
+		return common.Address{}, fmt.Errorf("%w: failed to resolve pool: %w", ErrRPCFailure, err)
+	}
+
+	if poolAddr == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("%w: no pair exists for %s/%s on factory %s", ErrPoolNotFound, srcToken.Hex(), dstToken.Hex(), factoryAddr.Hex())
+	}
+
+	return poolAddr, nil
+}
+
+// EstimateSwapByTokens resolves the pool on factoryAddr and estimates the
+// swap with that factory's fee.
+func (se *SwapEstimator) EstimateSwapByTokens(ctx context.Context, factoryAddr, srcToken, dstToken common.Address, srcAmount *big.Int) (common.Address, *SwapEstimate, error) {
+	poolAddr, err := se.ResolvePool(ctx, factoryAddr, srcToken, dstToken)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+
+	opts := se.DefaultOptions()
+	opts.Fee = se.feeForFactory(factoryAddr)
+	estimate, err := se.EstimateSwapWithOptions(ctx, poolAddr, srcToken, dstToken, srcAmount, opts)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+
+	return poolAddr, estimate, nil
+}
+
+func (se *SwapEstimator) estimateByTokensHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	srcStr := r.URL.Query().Get("src")
+	dstStr := r.URL.Query().Get("dst")
+	srcAmountStr := r.URL.Query().Get("src_amount")
+
+	if srcStr == "" || dstStr == "" || srcAmountStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameters: src, dst, src_amount")
+		return
+	}
+
+	tokens, err := se.resolveSwapTokens(srcStr, dstStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	srcAmount, err := se.parseSrcAmount(srcAmountStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	factoryAddr := se.factory
+	if factoryStr := r.URL.Query().Get("factory"); factoryStr != "" {
+		factoryAddr, err = parseAddress("factory", factoryStr)
+		if err != nil {
+			writeRequestError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	poolAddr, estimate, err := se.EstimateSwapByTokens(ctx, factoryAddr, tokens.Src, tokens.Dst, srcAmount)
+	if err != nil {
+		slog.WarnContext(ctx, "estimate by tokens failed", "src", srcStr, "dst", dstStr, "src_amount", srcAmountStr, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	response := EstimateResponse{
+		Pool:        poolAddr.Hex(),
+		DstAmount:   estimate.AmountOut.String(),
+		PriceImpact: estimate.PriceImpact.FloatString(4),
+		FeeAmount:   estimate.FeeAmount.String(),
+		WrapsETH:    tokens.WrapSrc,
+		UnwrapsWETH: tokens.UnwrapDst,
+		Token0:      estimate.Token0.Hex(),
+		Token1:      estimate.Token1.Hex(),
+		ZeroForOne:  estimate.ZeroForOne,
+	}
+	json.NewEncoder(w).Encode(response)
+}
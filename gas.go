@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// defaultSwapGasLimit is a typical gas cost of a single-hop Router02 swap.
+// Estimating it with eth_estimateGas would need a funded sender that has
+// approved the router, which a quote doesn't have.
+const defaultSwapGasLimit = 150000
+
+var defaultPriorityFee = big.NewInt(params.GWei)
+
+var errNoBaseFee = errors.New("node returned no base fee; the chain may not support EIP-1559")
+
+// GasEstimate prices the swap transaction under EIP-1559.
+type GasEstimate struct {
+	GasLimit             uint64 `json:"gas_limit"`
+	BaseFeePerGas        string `json:"base_fee_per_gas"`
+	MaxPriorityFeePerGas string `json:"max_priority_fee_per_gas"`
+	// MaxFeePerGas leaves room for the base fee to double before inclusion
+	MaxFeePerGas string `json:"max_fee_per_gas"`
+	// GasCostWei is what the swap costs at the current base fee
+	GasCostWei string `json:"gas_cost_wei"`
+}
+
+// GetBaseFee returns the base fee of the latest block. It ignores BLOCK_TAG,
+// since a transaction pays the fee of the block it lands in.
+func (ec *EthereumClient) GetBaseFee(ctx context.Context) (*big.Int, error) {
+	header, err := ec.getHeader(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.BaseFee == nil {
+		return nil, errNoBaseFee
+	}
+	return header.BaseFee, nil
+}
+
+func (se *SwapEstimator) SetSwapGasLimit(gasLimit uint64) {
+	se.swapGasLimit = gasLimit
+}
+
+func (se *SwapEstimator) SetPriorityFee(priorityFee *big.Int) {
+	se.priorityFee = priorityFee
+}
+
+// EstimateGas prices a swap of swapGasLimit gas at the latest base fee plus
+// the configured priority fee.
+func (se *SwapEstimator) EstimateGas(ctx context.Context) (*GasEstimate, error) {
+	baseFee, err := se.ethClient.GetBaseFee(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	gasPrice := new(big.Int).Add(baseFee, se.priorityFee)
+	maxFee := new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), se.priorityFee)
+	gasCost := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(se.swapGasLimit))
+
+	return &GasEstimate{
+		GasLimit:             se.swapGasLimit,
+		BaseFeePerGas:        baseFee.String(),
+		MaxPriorityFeePerGas: se.priorityFee.String(),
+		MaxFeePerGas:         maxFee.String(),
+		GasCostWei:           gasCost.String(),
+	}, nil
+}
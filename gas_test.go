@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestEstimateHandlerGasWithoutBaseFee(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+	})
+	se := NewSwapEstimator(chain)
+
+	req := httptest.NewRequest(http.MethodGet, "/estimate?pool="+testPool.Hex()+"&src="+testToken0.Hex()+"&dst="+testToken1.Hex()+"&src_amount=1000000000000000000&include_gas=true", nil)
+	rec := httptest.NewRecorder()
+	se.estimateHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	var resp EstimateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.DstAmount != "1974316068794122597" {
+		t.Errorf("dst_amount = %s, want 1974316068794122597", resp.DstAmount)
+	}
+	if resp.Gas != nil {
+		t.Errorf("gas = %+v, want none", resp.Gas)
+	}
+	if len(resp.Warnings) != 1 {
+		t.Errorf("warnings = %q, want one about gas", resp.Warnings)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	estimatorv1 "uniswap-v2-estimator/proto/estimator/v1"
+)
+
+const defaultGRPCListenAddr = ":50051"
+
+// estimatorGRPCServer serves estimator.v1.Estimator from the same
+// SwapEstimator as the REST API.
+type estimatorGRPCServer struct {
+	estimatorv1.UnimplementedEstimatorServer
+	se *SwapEstimator
+}
+
+// newGRPCServer runs interceptors, such as rateLimitInterceptor, in order
+// before each call.
+func newGRPCServer(se *SwapEstimator, interceptors ...grpc.UnaryServerInterceptor) *grpc.Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	estimatorv1.RegisterEstimatorServer(srv, &estimatorGRPCServer{se: se})
+	return srv
+}
+
+// rateLimitInterceptor applies rl to gRPC calls, so they draw on the same
+// per-IP buckets as REST requests.
+func rateLimitInterceptor(rl *ipRateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if delay := rl.reserve(peerIP(ctx)); delay > 0 {
+			return nil, status.Errorf(codes.ResourceExhausted, "Rate limit exceeded, retry in %s", delay.Round(time.Millisecond))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// loadShedInterceptor makes gRPC calls take a slot from l, so they count
+// towards the same MAX_IN_FLIGHT as REST requests.
+func loadShedInterceptor(l *inFlightLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !l.acquire() {
+			return nil, status.Error(codes.Unavailable, "Server overloaded, try again shortly")
+		}
+		defer l.release()
+		return handler(ctx, req)
+	}
+}
+
+// peerIP is the gRPC counterpart of clientIP.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// Estimate mirrors GET /estimate without its optional parameters. src and
+// dst may be ETH, as in the REST API.
+func (s *estimatorGRPCServer) Estimate(ctx context.Context, req *estimatorv1.EstimateRequest) (*estimatorv1.EstimateResponse, error) {
+	if req.GetPool() == "" || req.GetSrc() == "" || req.GetDst() == "" || req.GetSrcAmount() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Missing required fields: pool, src, dst, src_amount")
+	}
+
+	poolAddr, err := parseAddress("pool", req.GetPool())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	tokens, err := s.se.resolveSwapTokens(req.GetSrc(), req.GetDst())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	srcAmount, err := s.se.parseSrcAmount(req.GetSrcAmount())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx, cancel := s.se.withRPCTimeout(ctx)
+	defer cancel()
+
+	estimate, err := s.se.EstimateSwap(ctx, poolAddr, tokens.Src, tokens.Dst, srcAmount)
+	if err != nil {
+		slog.WarnContext(ctx, "gRPC estimate failed", "pool", req.GetPool(), "src", req.GetSrc(), "dst", req.GetDst(), "src_amount", req.GetSrcAmount(), "error", err)
+		return nil, grpcEstimateError(err)
+	}
+
+	return &estimatorv1.EstimateResponse{DstAmount: estimate.AmountOut.String()}, nil
+}
+
+// grpcEstimateError converts an estimate error to the gRPC status matching
+// the HTTP status the REST API returns for it, with the same message.
+func grpcEstimateError(err error) error {
+	return status.Error(grpcCode(estimateErrorStatus(err)), estimateErrorMessage(err))
+}
+
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
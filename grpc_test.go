@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	estimatorv1 "uniswap-v2-estimator/proto/estimator/v1"
+)
+
+// newTestGRPCClient serves se over an in-memory listener and returns a
+// client connected to it.
+func newTestGRPCClient(t *testing.T, se *SwapEstimator, interceptors ...grpc.UnaryServerInterceptor) estimatorv1.EstimatorClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := newGRPCServer(se, interceptors...)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return estimatorv1.NewEstimatorClient(conn)
+}
+
+func TestGRPCEstimate(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+	})
+	client := newTestGRPCClient(t, NewSwapEstimator(chain))
+
+	tests := []struct {
+		name     string
+		req      *estimatorv1.EstimateRequest
+		wantCode codes.Code
+		want     string
+	}{
+		{
+			name: "token0 to token1",
+			req:  &estimatorv1.EstimateRequest{Pool: testPool.Hex(), Src: testToken0.Hex(), Dst: testToken1.Hex(), SrcAmount: "1000000000000000000"},
+			want: "1974316068794122597",
+		},
+		{
+			name: "ETH in",
+			req:  &estimatorv1.EstimateRequest{Pool: testPool.Hex(), Src: "ETH", Dst: testToken0.Hex(), SrcAmount: "1000000000000000000"},
+			want: "496027303890107812",
+		},
+		{
+			name:     "missing field",
+			req:      &estimatorv1.EstimateRequest{Pool: testPool.Hex(), Src: testToken0.Hex(), SrcAmount: "1"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "bad address",
+			req:      &estimatorv1.EstimateRequest{Pool: "0x1234", Src: testToken0.Hex(), Dst: testToken1.Hex(), SrcAmount: "1"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "zero amount",
+			req:      &estimatorv1.EstimateRequest{Pool: testPool.Hex(), Src: testToken0.Hex(), Dst: testToken1.Hex(), SrcAmount: "0"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "unknown pool",
+			req:      &estimatorv1.EstimateRequest{Pool: testOther.Hex(), Src: testToken0.Hex(), Dst: testToken1.Hex(), SrcAmount: "1000"},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "token not in pool",
+			req:      &estimatorv1.EstimateRequest{Pool: testPool.Hex(), Src: testOther.Hex(), Dst: testToken1.Hex(), SrcAmount: "1000"},
+			wantCode: codes.FailedPrecondition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Estimate(context.Background(), tt.req)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("code = %s, want %s (err %v)", got, tt.wantCode, err)
+			}
+			if err == nil && resp.GetDstAmount() != tt.want {
+				t.Errorf("dst_amount = %s, want %s", resp.GetDstAmount(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCRateLimit(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+	})
+	// A burst of one, refilled far slower than the test runs
+	limiter := newIPRateLimiter(0.001, 1)
+	defer limiter.Close()
+	client := newTestGRPCClient(t, NewSwapEstimator(chain), rateLimitInterceptor(limiter))
+
+	req := &estimatorv1.EstimateRequest{Pool: testPool.Hex(), Src: testToken0.Hex(), Dst: testToken1.Hex(), SrcAmount: "1000"}
+	if _, err := client.Estimate(context.Background(), req); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if _, err := client.Estimate(context.Background(), req); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second call err = %v, want %s", err, codes.ResourceExhausted)
+	}
+}
+
+func TestGRPCLoadShed(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+	})
+	inFlight := newInFlightLimiter(1)
+	client := newTestGRPCClient(t, NewSwapEstimator(chain), loadShedInterceptor(inFlight))
+
+	req := &estimatorv1.EstimateRequest{Pool: testPool.Hex(), Src: testToken0.Hex(), Dst: testToken1.Hex(), SrcAmount: "1000"}
+
+	// Hold the only slot, as a REST request in flight would
+	if !inFlight.acquire() {
+		t.Fatal("acquire failed on an idle limiter")
+	}
+	if _, err := client.Estimate(context.Background(), req); status.Code(err) != codes.Unavailable {
+		t.Fatalf("err = %v, want %s while the slot is taken", err, codes.Unavailable)
+	}
+
+	inFlight.release()
+	if _, err := client.Estimate(context.Background(), req); err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+}
+
+func TestGRPCEstimateErrorHidesInternalErrors(t *testing.T) {
+	err := grpcEstimateError(errImpossibleQuote)
+	if status.Code(err) != codes.Internal || status.Convert(err).Message() != "Failed to estimate swap" {
+		t.Errorf("err = %v, want INTERNAL with a generic message", err)
+	}
+}
+
+func TestGRPCCode(t *testing.T) {
+	for httpStatus, want := range map[int]codes.Code{
+		http.StatusBadRequest:          codes.InvalidArgument,
+		http.StatusForbidden:           codes.PermissionDenied,
+		http.StatusNotFound:            codes.NotFound,
+		http.StatusUnprocessableEntity: codes.FailedPrecondition,
+		http.StatusBadGateway:          codes.Unavailable,
+		http.StatusServiceUnavailable:  codes.Unavailable,
+		http.StatusGatewayTimeout:      codes.DeadlineExceeded,
+		http.StatusInternalServerError: codes.Internal,
+	} {
+		if got := grpcCode(httpStatus); got != want {
+			t.Errorf("grpcCode(%d) = %s, want %s", httpStatus, got, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// gzipMinSize is the smallest response worth compressing; anything shorter,
+// such as /health, is sent as is.
+const gzipMinSize = 1024
+
+// gzipMiddleware compresses responses for clients that send
+// Accept-Encoding: gzip. Like corsMiddleware it wraps the whole router.
+// WebSocket upgrades are passed through since they need to hijack the
+// connection.
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if !acceptsGzip(r) || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.Close()
+
+		next.ServeHTTP(gw, r)
+	})
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.TrimSpace(name) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response and only switches to
+// gzip once it grows past gzipMinSize. Responses that already carry a
+// Content-Encoding (e.g. /metrics) are passed through untouched.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         []byte
+	gz          *gzip.Writer
+	passthrough bool
+}
+
+func (gw *gzipResponseWriter) WriteHeader(status int) {
+	if gw.passthrough || gw.gz != nil {
+		return
+	}
+	if gw.status == 0 {
+		gw.status = status
+	}
+}
+
+func (gw *gzipResponseWriter) Write(p []byte) (int, error) {
+	if gw.passthrough {
+		return gw.ResponseWriter.Write(p)
+	}
+	if gw.gz != nil {
+		return gw.gz.Write(p)
+	}
+
+	gw.buf = append(gw.buf, p...)
+	if len(gw.buf) < gzipMinSize {
+		return len(p), nil
+	}
+
+	if err := gw.start(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// start commits the headers and flushes the buffered bytes, compressed unless
+// the handler set its own Content-Encoding.
+func (gw *gzipResponseWriter) start() error {
+	header := gw.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" {
+		gw.passthrough = true
+	} else {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		gw.gz = gzip.NewWriter(gw.ResponseWriter)
+	}
+
+	if gw.status != 0 {
+		gw.ResponseWriter.WriteHeader(gw.status)
+	}
+
+	buf := gw.buf
+	gw.buf = nil
+	if gw.gz != nil {
+		_, err := gw.gz.Write(buf)
+		return err
+	}
+	_, err := gw.ResponseWriter.Write(buf)
+	return err
+}
+
+// Close finishes the gzip stream, or writes a response that stayed under
+// gzipMinSize uncompressed.
+func (gw *gzipResponseWriter) Close() error {
+	if gw.gz != nil {
+		return gw.gz.Close()
+	}
+	if gw.passthrough {
+		return nil
+	}
+
+	gw.passthrough = true
+	if gw.status != 0 {
+		gw.ResponseWriter.WriteHeader(gw.status)
+	}
+	if len(gw.buf) > 0 {
+		_, err := gw.ResponseWriter.Write(gw.buf)
+		return err
+	}
+	return nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (gw *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
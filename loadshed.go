@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const defaultMaxInFlight = 100
+
+var (
+	inFlightRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "estimator_in_flight_requests",
+		Help: "Number of estimate requests currently being served.",
+	})
+
+	shedRequestsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "estimator_shed_requests_total",
+		Help: "Number of requests rejected because too many were in flight.",
+	})
+)
+
+// loadShedExempt are the paths that never hold a slot: probes and scrapers
+// must keep working under load, and a quote stream would hold its slot for as
+// long as the socket stays open.
+var loadShedExempt = map[string]bool{
+	"/health":       true,
+	"/live":         true,
+	"/metrics":      true,
+	"/version":      true,
+	"/openapi.json": true,
+	"/ws/quote":     true,
+}
+
+// inFlightLimiter caps the requests served concurrently. Requests over the
+// cap are rejected at once rather than queued, so a spike can't pile up
+// behind a struggling node and push every request past its timeout.
+type inFlightLimiter struct {
+	slots chan struct{}
+}
+
+func newInFlightLimiter(max int) *inFlightLimiter {
+	return &inFlightLimiter{slots: make(chan struct{}, max)}
+}
+
+func (l *inFlightLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if loadShedExempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !l.acquire() {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusServiceUnavailable, CodeOverloaded, "Server overloaded, try again shortly")
+			return
+		}
+		defer l.release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acquire takes a slot, or reports false and counts the request as shed
+// when none is free.
+func (l *inFlightLimiter) acquire() bool {
+	select {
+	case l.slots <- struct{}{}:
+		inFlightRequests.Inc()
+		return true
+	default:
+		shedRequestsTotal.Inc()
+		return false
+	}
+}
+
+func (l *inFlightLimiter) release() {
+	inFlightRequests.Dec()
+	<-l.slots
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+func parseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
+func newLogger(out io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(requestIDHandler{slog.NewJSONHandler(out, &slog.HandlerOptions{Level: level})})
+}
+
+// fatal logs at error level and exits, replacing log.Fatal for startup errors.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// fastRequest reports whether a request finished under the slow request
+// threshold, in which case a successful request is only logged at debug.
+func fastRequest(elapsed, slowThreshold time.Duration) bool {
+	return slowThreshold > 0 && elapsed < slowThreshold
+}
+
+func logEstimateRequest(ctx context.Context, req EstimateRequest, start time.Time, outcome string, err error, slowThreshold time.Duration) {
+	elapsed := time.Since(start)
+	attrs := []any{
+		"pool", req.Pool,
+		"src", req.Src,
+		"dst", req.Dst,
+		"src_amount", req.SrcAmount,
+		"duration_ms", elapsed.Milliseconds(),
+		"outcome", outcome,
+	}
+
+	if err != nil {
+		slog.WarnContext(ctx, "estimate request", append(attrs, "error", err)...)
+		return
+	}
+
+	level := slog.LevelInfo
+	if outcome != "invalid_request" && fastRequest(elapsed, slowThreshold) {
+		level = slog.LevelDebug
+	}
+	slog.Log(ctx, level, "estimate request", attrs...)
+}
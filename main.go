@@ -1,77 +1,366 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/big"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
 )
 
-const pairABI = `[
-	{
-		"constant": true,
-		"inputs": [],
-		"name": "getReserves",
-		"outputs": [
-			{"name": "reserve0", "type": "uint112"},
-			{"name": "reserve1", "type": "uint112"},
-			{"name": "blockTimestampLast", "type": "uint32"}
-		],
-		"type": "function"
-	},
-	{
-		"constant": true,
-		"inputs": [],
-		"name": "token0",
-		"outputs": [{"name": "", "type": "address"}],
-		"type": "function"
-	},
-	{
-		"constant": true,
-		"inputs": [],
-		"name": "token1",
-		"outputs": [{"name": "", "type": "address"}],
-		"type": "function"
-	}
-]`
-
 type EthereumClient struct {
-	client *ethclient.Client
-	abi    abi.ABI
+	// endpoints holds one connection per configured node URL; eth_call is
+	// spread across them round-robin
+	endpoints    []*nodeEndpoint
+	nextEndpoint atomic.Uint64
+
+	abi          abi.ABI
+	multicallABI abi.ABI
+	erc20ABI     abi.ABI
+	factoryABI   abi.ABI
+	quoterV3ABI  abi.ABI
+	routerABI    abi.ABI
+	maxRetries   int
+	// breaker is nil when the circuit breaker is disabled
+	breaker             *circuitBreaker
+	reservesFromStorage bool
+	// blockTag is read instead of the latest block when a call doesn't name
+	// a block; nil means latest
+	blockTag *big.Int
+	// reservesCache is nil when reserves caching is disabled
+	reservesCache *reservesCache
+
+	decimalsMu    sync.RWMutex
+	decimalsCache map[common.Address]uint8
+
+	pairTokensMu sync.RWMutex
+	pairTokens   map[pairTokenKey]common.Address
+
+	tokenStringsMu sync.RWMutex
+	tokenStrings   map[tokenStringKey]string
+
+	multicallCodeMu sync.Mutex
+	multicallCode   []byte
+
+	chainIDMu sync.Mutex
+	chainID   *big.Int
 }
 
 type PoolReserves struct {
 	Reserve0 *big.Int
 	Reserve1 *big.Int
+	// BlockTimestampLast is the (mod 2^32) timestamp of the block in which
+	// the reserves were last updated
+	BlockTimestampLast uint32
+}
+
+// K returns the constant-product invariant reserve0 * reserve1.
+func (pr *PoolReserves) K() *big.Int {
+	return new(big.Int).Mul(pr.Reserve0, pr.Reserve1)
+}
+
+// pairTokenKey identifies a cached token0 or token1 lookup.
+type pairTokenKey struct {
+	pair   common.Address
+	method string
+}
+
+// PoolState is a pair's reserves together with the tokens they belong to.
+type PoolState struct {
+	*PoolReserves
+	Token0 common.Address
+	Token1 common.Address
+}
+
+// directionalReserves orders a pool's reserves for a swap from src to dst.
+type directionalReserves struct {
+	*PoolState
+	ZeroForOne bool
+	ReserveIn  *big.Int
+	ReserveOut *big.Int
+}
+
+// ReserveReader reads the pair state needed to price a swap.
+type ReserveReader interface {
+	GetReserves(ctx context.Context, pairAddr common.Address, blockNumber *big.Int) (*PoolReserves, error)
+	GetToken0(ctx context.Context, pairAddr common.Address, blockNumber *big.Int) (common.Address, error)
+	GetToken1(ctx context.Context, pairAddr common.Address, blockNumber *big.Int) (common.Address, error)
+}
+
+// ChainReader is everything SwapEstimator reads from a node. *EthereumClient
+// implements it; tests can substitute a fake with canned state.
+type ChainReader interface {
+	ReserveReader
+	GetPair(ctx context.Context, factoryAddr, tokenA, tokenB common.Address) (common.Address, error)
+	GetAllPairsLength(ctx context.Context, factoryAddr common.Address) (uint64, error)
+	GetAllPairs(ctx context.Context, factoryAddr common.Address, offset, count uint64) ([]common.Address, error)
+	GetPairTokensBatch(ctx context.Context, pairs []common.Address) ([][2]common.Address, error)
+	GetReservesBatch(ctx context.Context, pairs []common.Address) ([]*PoolReserves, []error, error)
+	GetPairDecimals(ctx context.Context, tokenA, tokenB common.Address) (uint8, uint8, error)
+	GetSymbol(ctx context.Context, tokenAddr common.Address) (string, error)
+	GetBlockTimestamp(ctx context.Context, blockNumber *big.Int) (uint64, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+	SimulateTransfer(ctx context.Context, pool, token common.Address, amount, blockNumber *big.Int) (*big.Int, error)
+	QuoteExactInputSingleV3(ctx context.Context, quoter, tokenIn, tokenOut common.Address, amountIn *big.Int, feeTier uint32) (*V3Quote, error)
+	GetAmountsOut(ctx context.Context, router common.Address, amountIn *big.Int, path []common.Address, blockNumber *big.Int) ([]*big.Int, error)
+	PairCalldata(pairAddr common.Address) ([]PackedCall, error)
+	GetBaseFee(ctx context.Context) (*big.Int, error)
+	GetBlockNumber(ctx context.Context, blockNumber *big.Int) (uint64, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+	GetAllowance(ctx context.Context, token, owner, spender common.Address, blockNumber *big.Int) (*big.Int, error)
 }
 
 type SwapEstimator struct {
-	ethClient *EthereumClient
+	ethClient ChainReader
+	chains    *ChainClients
+	factory   common.Address
+	// factoryFees overrides fee for pools resolved through a given factory
+	factoryFees map[common.Address]SwapFee
+	quoterV3    common.Address
+	router      common.Address
+	weth        common.Address
+	fee         SwapFee
+	rpcTimeout  time.Duration
+	// allowNodeOverride lets requests pick their own node with node_url
+	allowNodeOverride bool
+	maxSrcAmount      *big.Int
+	responseCache     *responseCache
+	// poolAllowlist is nil unless POOL_ALLOWLIST restricts the pools served
+	poolAllowlist map[common.Address]bool
+	swapGasLimit  uint64
+	priorityFee   *big.Int
+	// poolDenylist is nil unless POOL_DENYLIST is set
+	poolDenylist *poolDenylist
+	// usdPricePool is the WETH/stablecoin pair behind liquidity_usd; zero
+	// when USD_PRICE_POOL isn't set
+	usdPricePool common.Address
+	// slowRequestThreshold demotes faster successful estimate logs to debug;
+	// 0 logs every estimate at info
+	slowRequestThreshold time.Duration
+}
+
+const defaultRPCTimeout = 5 * time.Second
+
+// defaultMaxSrcAmount is the largest value a uint112 pair reserve can hold;
+// no V2 pool can absorb more than that.
+var defaultMaxSrcAmount = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 112), big.NewInt(1))
+
+// healthCheckTimeout bounds the node call made by /health so probes fail fast.
+const healthCheckTimeout = 2 * time.Second
+
+// SwapFee is the fraction of the input that reaches the pool after the LP fee,
+// e.g. 997/1000 for Uniswap V2's 0.3%.
+type SwapFee struct {
+	Numerator   int64
+	Denominator int64
+}
+
+var DefaultSwapFee = SwapFee{Numerator: 997, Denominator: 1000}
+
+// EstimateOptions carries the per-request overrides for an estimate.
+type EstimateOptions struct {
+	Fee SwapFee
+	// BlockNumber pins the pool state to a historical block; nil means latest
+	BlockNumber *big.Int
+	// TransferFeeBps is withheld from the input by a fee-on-transfer src token
+	// before it reaches the pool
+	TransferFeeBps int64
+	// Curve prices the swap; nil means constant product
+	Curve PriceCurve
+	// Amplification is the A used by the StableSwap curve
+	Amplification int64
+	// PoolState is the pool's state when the caller already read it, e.g.
+	// for a batch; nil means it is read at BlockNumber
+	PoolState *PoolState
+}
+
+func SwapFeeFromBps(feeBps int64) SwapFee {
+	return SwapFee{Numerator: 10000 - feeBps, Denominator: 10000}
+}
+
+type SwapEstimate struct {
+	// AmountIn is the input the pool receives, after any transfer fee
+	AmountIn   *big.Int
+	AmountOut  *big.Int
+	ReserveIn  *big.Int
+	ReserveOut *big.Int
+	// PriceImpact is expressed as a percentage of the spot price
+	PriceImpact *big.Rat
+	// FeeAmount is the part of the input paid to LPs, in src token units
+	FeeAmount *big.Int
+	// K and BlockTimestampLast describe the pool state the estimate used
+	K                  *big.Int
+	BlockTimestampLast uint32
+	Token0             common.Address
+	Token1             common.Address
+	// ZeroForOne is true when src is token0, i.e. ReserveIn is reserve0
+	ZeroForOne bool
+}
+
+// EstimateRequest mirrors the /estimate query parameters so GET and POST
+// requests share the same validation.
+type EstimateRequest struct {
+	Pool string `json:"pool"`
+	Src  string `json:"src"`
+	// Dst may be left out to swap into the pool's other token
+	Dst       string `json:"dst,omitempty"`
+	SrcAmount string `json:"src_amount"`
+	FeeBps    string `json:"fee_bps,omitempty"`
+	Block     string `json:"block,omitempty"`
+	// BlockTag is latest, safe or finalized; it can't be combined with Block
+	BlockTag string `json:"block_tag,omitempty"`
+	Format   string `json:"format,omitempty"`
+	// Precision caps the decimal places shown with format=decimal
+	Precision string `json:"precision,omitempty"`
+	ChainID   string `json:"chain_id,omitempty"`
+	// SlippageBps adds min_dst_amount to the response when set
+	SlippageBps string `json:"slippage_bps,omitempty"`
+	// IntegratorFeeBps adds net_dst_amount and integrator_fee_amount when set
+	IntegratorFeeBps string `json:"integrator_fee_bps,omitempty"`
+	// IncludeState adds k and the reserves' age to the response when "true"
+	IncludeState string `json:"include_state,omitempty"`
+	// IncludeMetadata adds the tokens' symbols to the response when "true"
+	IncludeMetadata string `json:"include_metadata,omitempty"`
+	// IncludeGas adds the swap transaction's gas cost when "true"
+	IncludeGas string `json:"include_gas,omitempty"`
+	// IncludeRate adds the exchange rate as an exact fraction when "true"
+	IncludeRate string `json:"include_rate,omitempty"`
+	// IncludeLiquidityUSD adds liquidity_usd when "true" and USD_PRICE_POOL
+	// is set
+	IncludeLiquidityUSD string `json:"include_liquidity_usd,omitempty"`
+	// NodeURL is ignored unless ALLOW_NODE_OVERRIDE is enabled
+	NodeURL string `json:"node_url,omitempty"`
+	// TransferFeeBps reduces the input for fee-on-transfer src tokens
+	TransferFeeBps   string `json:"transfer_fee_bps,omitempty"`
+	CheckTransferFee string `json:"check_transfer_fee,omitempty"`
+	// CheckRebasing adds possible_rebasing when "true"
+	CheckRebasing string `json:"check_rebasing,omitempty"`
+	// Engine "router" takes dst_amount from the router's getAmountsOut
+	// instead of the local math
+	Engine string `json:"engine,omitempty"`
+	// Curve is constant_product (the default) or stableswap
+	Curve string `json:"curve,omitempty"`
+	// Amplification is StableSwap's A; only valid with curve=stableswap
+	Amplification string `json:"amplification,omitempty"`
+	// NumberFormat "number" encodes amounts as JSON numbers instead of
+	// strings
+	NumberFormat string `json:"number_format,omitempty"`
+	// Owner adds needs_approval and the owner's src allowance for the router
+	Owner string `json:"owner,omitempty"`
+	// Envelope "true" wraps the response in data alongside meta
+	Envelope string `json:"envelope,omitempty"`
+	// Revalidate "true" bypasses the caches and adds block_number
+	Revalidate string `json:"revalidate,omitempty"`
+	// Debug set to "calldata" returns the packed pair calls instead of an
+	// estimate
+	Debug string `json:"debug,omitempty"`
 }
 
 type EstimateResponse struct {
-	DstAmount string `json:"dst_amount"`
+	Pool string `json:"pool,omitempty"`
+	// Dst is only set when the request left dst out and it was inferred
+	// from the pool
+	Dst         string `json:"dst,omitempty"`
+	DstAmount   string `json:"dst_amount"`
+	PriceImpact string `json:"price_impact"`
+	// FeeAmount is denominated in the src token
+	FeeAmount string `json:"fee_amount"`
+	// MinDstAmount is dst_amount less the requested slippage tolerance
+	MinDstAmount string `json:"min_dst_amount,omitempty"`
+	// IntegratorFeeAmount is taken from dst_amount, the pool's gross output,
+	// leaving NetDstAmount for the user
+	IntegratorFeeAmount string `json:"integrator_fee_amount,omitempty"`
+	NetDstAmount        string `json:"net_dst_amount,omitempty"`
+	// WrapsETH and UnwrapsWETH flag that native ETH was quoted via WETH
+	WrapsETH    bool   `json:"wraps_eth,omitempty"`
+	UnwrapsWETH bool   `json:"unwraps_weth,omitempty"`
+	Token0      string `json:"token0"`
+	Token1      string `json:"token1"`
+	ZeroForOne  bool   `json:"zero_for_one"`
+	// K, BlockTimestampLast and ReservesAgeSeconds are only set when the
+	// request asks for include_state
+	K                  string  `json:"k,omitempty"`
+	BlockTimestampLast uint32  `json:"block_timestamp_last,omitempty"`
+	ReservesAgeSeconds *uint64 `json:"reserves_age_seconds,omitempty"`
+	// Gas is only set when the request asks for include_gas
+	Gas *GasEstimate `json:"gas,omitempty"`
+	// Rate is only set when the request asks for include_rate
+	Rate *ExchangeRate `json:"rate,omitempty"`
+	// LiquidityUSD is only set when the request asks for
+	// include_liquidity_usd and the pool could be valued
+	LiquidityUSD string `json:"liquidity_usd,omitempty"`
+	// SrcSymbol and DstSymbol are only set when the request asks for
+	// include_metadata and the token implements symbol()
+	SrcSymbol string `json:"src_symbol,omitempty"`
+	DstSymbol string `json:"dst_symbol,omitempty"`
+	// NeedsApproval and Allowance are only set when the request passes owner;
+	// NeedsApproval is true if the router may move less than src_amount
+	NeedsApproval *bool  `json:"needs_approval,omitempty"`
+	Allowance     string `json:"allowance,omitempty"`
+	// BlockNumber is only set when the request asks for revalidate; it is
+	// the block the reserves were read at
+	BlockNumber uint64 `json:"block_number,omitempty"`
+	// PossibleRebasing is only set when the request asks for check_rebasing
+	PossibleRebasing *bool `json:"possible_rebasing,omitempty"`
+	// numberAmounts encodes the amounts as JSON numbers; see MarshalJSON
+	numberAmounts bool
+	// Warnings flags conditions that may make the estimate inaccurate, such
+	// as a detected fee-on-transfer token
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type EstimateExactOutResponse struct {
+	SrcAmount string `json:"src_amount"`
+}
+
+type EstimateRouteResponse struct {
+	DstAmount string   `json:"dst_amount"`
+	Amounts   []string `json:"amounts"`
+}
+
+type HealthResponse struct {
+	Status      string `json:"status"`
+	BlockNumber uint64 `json:"block_number,omitempty"`
+	Error       string `json:"error,omitempty"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Code is one of the Code* constants
+	Code string `json:"code"`
 }
 
+// NewEthereumClient connects to nodeURL, which may be a comma-separated list
+// of equivalent nodes to spread calls across.
 func NewEthereumClient(nodeURL string) (*EthereumClient, error) {
-	client, err := ethclient.Dial(nodeURL)
+	endpoints, err := dialEndpoints(nodeURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Ethereum node: %w", err)
+		return nil, err
 	}
 
 	parsedABI, err := abi.JSON(strings.NewReader(pairABI))
@@ -79,202 +368,1635 @@ func NewEthereumClient(nodeURL string) (*EthereumClient, error) {
 		return nil, fmt.Errorf("failed to parse ABI: %w", err)
 	}
 
+	parsedMulticallABI, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse multicall ABI: %w", err)
+	}
+
+	parsedERC20ABI, err := abi.JSON(strings.NewReader(erc20ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ERC20 ABI: %w", err)
+	}
+
+	parsedFactoryABI, err := abi.JSON(strings.NewReader(factoryABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse factory ABI: %w", err)
+	}
+
+	parsedQuoterV3ABI, err := abi.JSON(strings.NewReader(quoterV3ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse V3 quoter ABI: %w", err)
+	}
+
+	parsedRouterABI, err := abi.JSON(strings.NewReader(routerABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse router ABI: %w", err)
+	}
+
 	return &EthereumClient{
-		client: client,
-		abi:    parsedABI,
+		endpoints:     endpoints,
+		abi:           parsedABI,
+		multicallABI:  parsedMulticallABI,
+		erc20ABI:      parsedERC20ABI,
+		factoryABI:    parsedFactoryABI,
+		quoterV3ABI:   parsedQuoterV3ABI,
+		routerABI:     parsedRouterABI,
+		maxRetries:    defaultRPCMaxRetries,
+		decimalsCache: make(map[common.Address]uint8),
+		pairTokens:    make(map[pairTokenKey]common.Address),
+		tokenStrings:  make(map[tokenStringKey]string),
 	}, nil
 }
 
-func (ec *EthereumClient) GetReserves(ctx context.Context, pairAddr common.Address) (*PoolReserves, error) {
+func (ec *EthereumClient) SetMaxRetries(maxRetries int) {
+	ec.maxRetries = maxRetries
+}
+
+// SetCircuitBreaker makes calls fail fast with ErrNodeUnavailable for
+// cooldown after threshold consecutive node failures. name labels the
+// breaker's metrics; a threshold of 0 disables it.
+func (ec *EthereumClient) SetCircuitBreaker(name string, threshold int, cooldown time.Duration) {
+	if threshold <= 0 {
+		ec.breaker = nil
+		return
+	}
+	ec.breaker = newCircuitBreaker(name, threshold, cooldown)
+}
+
+func (ec *EthereumClient) Close() {
+	for _, ep := range ec.endpoints {
+		ep.close()
+	}
+	if ec.reservesCache != nil {
+		ec.reservesCache.cancel()
+	}
+}
+
+// callContract executes a read-only call against the given block, or the
+// latest block when blockNumber is nil, failing over between endpoints and
+// retrying transient node errors.
+// method is only used to label metrics.
+func (ec *EthereumClient) callContract(ctx context.Context, method string, to common.Address, data []byte, blockNumber *big.Int) ([]byte, error) {
+	if err := ec.breaker.Allow(); err != nil {
+		return nil, err
+	}
 
-	data, err := ec.abi.Pack("getReserves")
+	blockNumber = ec.blockOrDefault(blockNumber)
+	msg := ethereum.CallMsg{To: &to, Data: data}
+	result, err := withRetry(ctx, ec.maxRetries, func() ([]byte, error) {
+		return ec.callEndpoints(ctx, method, msg, blockNumber)
+	})
+	ec.breaker.Record(err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to pack getReserves call: %w", err)
+		if blockNumber != nil && isMissingStateError(err) {
+			return nil, fmt.Errorf("%w: block %s: %v", ErrStateUnavailable, formatBlock(blockNumber), err)
+		}
+		return nil, err
+	}
+
+	// Calls to addresses without code succeed with no return data
+	if len(result) == 0 {
+		return nil, errEmptyResult
+	}
+
+	return result, nil
+}
+
+// isContractFailure reports whether a call reached the node but the target
+// contract reverted or returned data that doesn't match the expected ABI.
+func isContractFailure(err error) bool {
+	if errors.Is(err, errEmptyResult) || errors.Is(err, errMalformedResult) {
+		return true
+	}
+
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "execution reverted")
+}
+
+// isMissingStateError reports whether a node rejected a historical call
+// because it has pruned the state (i.e. it is not an archive node).
+func isMissingStateError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range []string{
+		"missing trie node",
+		"header not found",
+		"historical state",
+		"state is not available",
+		"state not available",
+		"required historical state unavailable",
+	} {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
 	}
+	return false
+}
 
-	result, err := ec.client.CallContract(ctx, ethereum.CallMsg{
-		To:   &pairAddr,
-		Data: data,
-	}, nil)
+func (ec *EthereumClient) GetReserves(ctx context.Context, pairAddr common.Address, blockNumber *big.Int) (*PoolReserves, error) {
+	if blockNumber == nil && ec.reservesCache != nil {
+		return ec.getCachedReserves(pairAddr, func() (*PoolReserves, error) {
+			return ec.fetchReserves(ctx, pairAddr, nil)
+		})
+	}
+	return ec.fetchReserves(ctx, pairAddr, blockNumber)
+}
+
+func (ec *EthereumClient) fetchReserves(ctx context.Context, pairAddr common.Address, blockNumber *big.Int) (*PoolReserves, error) {
+	if ec.reservesFromStorage {
+		return ec.GetReservesFromStorage(ctx, pairAddr, blockNumber)
+	}
+
+	data, err := ec.packPairCall("getReserves")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ec.callContract(ctx, "getReserves", pairAddr, data, blockNumber)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call getReserves: %w", err)
 	}
 
-	// Unpack the result
+	return ec.unpackReserves(result)
+}
+
+func (ec *EthereumClient) unpackReserves(result []byte) (*PoolReserves, error) {
 	unpacked, err := ec.abi.Unpack("getReserves", result)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unpack getReserves result: %w", err)
+	if err == nil && len(unpacked) == 3 {
+		reserve0, ok0 := unpacked[0].(*big.Int)
+		reserve1, ok1 := unpacked[1].(*big.Int)
+		blockTimestampLast, ok2 := unpacked[2].(uint32)
+		if ok0 && ok1 && ok2 {
+			return &PoolReserves{
+				Reserve0:           reserve0,
+				Reserve1:           reserve1,
+				BlockTimestampLast: blockTimestampLast,
+			}, nil
+		}
 	}
 
-	if len(unpacked) < 2 {
-		return nil, fmt.Errorf("unexpected getReserves result length")
+	// Some non-standard pairs return fewer words or pad the timestamp
+	// differently; decode the raw words before giving up
+	if reserves, ok := decodeReservesWords(result); ok {
+		return reserves, nil
 	}
 
-	reserve0, ok := unpacked[0].(*big.Int)
-	if !ok {
-		return nil, fmt.Errorf("failed to cast reserve0 to *big.Int")
+	if err == nil {
+		err = fmt.Errorf("unexpected output layout")
 	}
+	return nil, fmt.Errorf("%w: failed to unpack getReserves result %s: %w", errMalformedResult, rawResultHex(result), err)
+}
 
-	reserve1, ok := unpacked[1].(*big.Int)
-	if !ok {
-		return nil, fmt.Errorf("failed to cast reserve1 to *big.Int")
+// decodeReservesWords reads reserve0 and reserve1 from the first two 32-byte
+// words of result and blockTimestampLast from the low bits of the third, if
+// present. Reserves that don't fit in a uint112 mean result isn't reserves.
+func decodeReservesWords(result []byte) (*PoolReserves, bool) {
+	if len(result) < 64 {
+		return nil, false
+	}
+
+	reserve0 := new(big.Int).SetBytes(result[0:32])
+	reserve1 := new(big.Int).SetBytes(result[32:64])
+	if reserve0.Cmp(uint112Mask) > 0 || reserve1.Cmp(uint112Mask) > 0 {
+		return nil, false
+	}
+
+	var blockTimestampLast uint32
+	if len(result) >= 96 {
+		blockTimestampLast = binary.BigEndian.Uint32(result[92:96])
 	}
 
 	return &PoolReserves{
-		Reserve0: reserve0,
-		Reserve1: reserve1,
-	}, nil
+		Reserve0:           reserve0,
+		Reserve1:           reserve1,
+		BlockTimestampLast: blockTimestampLast,
+	}, true
+}
+
+// rawResultHex renders a call result for error messages, truncated so an
+// exotic contract can't flood the logs.
+func rawResultHex(result []byte) string {
+	const maxBytes = 256
+	if len(result) > maxBytes {
+		return hexutil.Encode(result[:maxBytes]) + fmt.Sprintf("... (%d bytes)", len(result))
+	}
+	return hexutil.Encode(result)
+}
+
+// BlockNumber returns the latest block number. It isn't retried so that health
+// checks report a struggling node promptly.
+func (ec *EthereumClient) BlockNumber(ctx context.Context) (uint64, error) {
+	start := time.Now()
+	client := ec.conn()
+	blockNumber, err := client.BlockNumber(ctx)
+	observeRPCCall("blockNumber", start, err)
+	ec.checkConnection(client, err)
+	return blockNumber, err
+}
+
+// SubscribeNewHead notifies ch of each new block. Nodes reached over HTTP
+// return rpc.ErrNotificationsUnsupported.
+func (ec *EthereumClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return ec.conn().SubscribeNewHead(ctx, ch)
 }
 
-func (ec *EthereumClient) GetToken0(ctx context.Context, pairAddr common.Address) (common.Address, error) {
-	data, err := ec.abi.Pack("token0")
+// GetBlockTimestamp returns the timestamp of the given block, or of the latest
+// block when blockNumber is nil.
+func (ec *EthereumClient) GetBlockTimestamp(ctx context.Context, blockNumber *big.Int) (uint64, error) {
+	header, err := ec.getHeader(ctx, ec.blockOrDefault(blockNumber))
 	if err != nil {
-		return common.Address{}, fmt.Errorf("failed to pack token0 call: %w", err)
+		return 0, err
 	}
 
-	result, err := ec.client.CallContract(ctx, ethereum.CallMsg{
-		To:   &pairAddr,
-		Data: data,
-	}, nil)
+	return header.Time, nil
+}
+
+// getHeader fetches the header of blockNumber as given, without applying
+// BLOCK_TAG; nil is the latest block.
+func (ec *EthereumClient) getHeader(ctx context.Context, blockNumber *big.Int) (*types.Header, error) {
+	header, err := withRetry(ctx, ec.maxRetries, func() (*types.Header, error) {
+		start := time.Now()
+		client := ec.conn()
+		header, err := client.HeaderByNumber(ctx, blockNumber)
+		observeRPCCall("getBlockHeader", start, err)
+		ec.checkConnection(client, err)
+		return header, err
+	})
 	if err != nil {
-		return common.Address{}, fmt.Errorf("failed to call token0: %w", err)
+		if blockNumber != nil && isMissingStateError(err) {
+			return nil, fmt.Errorf("%w: block %s: %v", ErrStateUnavailable, formatBlock(blockNumber), err)
+		}
+		return nil, fmt.Errorf("%w: failed to get block header: %w", ErrRPCFailure, err)
 	}
 
-	unpacked, err := ec.abi.Unpack("token0", result)
+	return header, nil
+}
+
+func (ec *EthereumClient) GetToken0(ctx context.Context, pairAddr common.Address, blockNumber *big.Int) (common.Address, error) {
+	return ec.getPairToken(ctx, pairAddr, "token0", blockNumber)
+}
+
+func (ec *EthereumClient) GetToken1(ctx context.Context, pairAddr common.Address, blockNumber *big.Int) (common.Address, error) {
+	return ec.getPairToken(ctx, pairAddr, "token1", blockNumber)
+}
+
+// getPairToken calls token0 or token1 on a pair. A pair's tokens are set once
+// at creation, so they are cached forever after the first successful read.
+func (ec *EthereumClient) getPairToken(ctx context.Context, pairAddr common.Address, method string, blockNumber *big.Int) (common.Address, error) {
+	key := pairTokenKey{pairAddr, method}
+
+	ec.pairTokensMu.RLock()
+	tokenAddr, ok := ec.pairTokens[key]
+	ec.pairTokensMu.RUnlock()
+	if ok {
+		return tokenAddr, nil
+	}
+
+	data, err := ec.packPairCall(method)
 	if err != nil {
-		return common.Address{}, fmt.Errorf("failed to unpack token0 result: %w", err)
+		return common.Address{}, err
+	}
+
+	result, err := ec.callContract(ctx, method, pairAddr, data, blockNumber)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to call %s: %w", method, err)
+	}
+
+	unpacked, err := ec.abi.Unpack(method, result)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: failed to unpack %s result: %w", errMalformedResult, method, err)
 	}
 
 	if len(unpacked) == 0 {
-		return common.Address{}, fmt.Errorf("empty token0 result")
+		return common.Address{}, fmt.Errorf("empty %s result", method)
 	}
 
-	token0Addr, ok := unpacked[0].(common.Address)
+	tokenAddr, ok = unpacked[0].(common.Address)
 	if !ok {
-		return common.Address{}, fmt.Errorf("failed to cast token0 to common.Address")
+		return common.Address{}, fmt.Errorf("failed to cast %s to common.Address", method)
 	}
 
-	return token0Addr, nil
+	// A zero address means this isn't a working pair, so leave it uncached
+	if tokenAddr != (common.Address{}) {
+		ec.pairTokensMu.Lock()
+		ec.pairTokens[key] = tokenAddr
+		ec.pairTokensMu.Unlock()
+	}
+
+	return tokenAddr, nil
+}
+
+func NewSwapEstimator(ethClient ChainReader) *SwapEstimator {
+	return NewSwapEstimatorWithFee(ethClient, DefaultSwapFee)
+}
+
+func (se *SwapEstimator) DefaultOptions() EstimateOptions {
+	return EstimateOptions{Fee: se.fee}
 }
 
-func NewSwapEstimator(ethClient *EthereumClient) *SwapEstimator {
+func NewSwapEstimatorWithFee(ethClient ChainReader, fee SwapFee) *SwapEstimator {
 	return &SwapEstimator{
-		ethClient: ethClient,
+		ethClient:    ethClient,
+		factory:      defaultFactoryAddress,
+		quoterV3:     defaultQuoterV3Address,
+		router:       defaultRouterAddress,
+		weth:         defaultWETHAddress,
+		fee:          fee,
+		rpcTimeout:   defaultRPCTimeout,
+		maxSrcAmount: defaultMaxSrcAmount,
+		swapGasLimit: defaultSwapGasLimit,
+		priorityFee:  defaultPriorityFee,
 	}
 }
 
-func (se *SwapEstimator) EstimateSwap(ctx context.Context, poolAddr, srcToken, dstToken common.Address, srcAmount *big.Int) (*big.Int, error) {
+func (se *SwapEstimator) SetRPCTimeout(timeout time.Duration) {
+	se.rpcTimeout = timeout
+}
+
+func (se *SwapEstimator) SetSlowRequestThreshold(threshold time.Duration) {
+	se.slowRequestThreshold = threshold
+}
+
+func (se *SwapEstimator) SetChainClients(chains *ChainClients) {
+	se.chains = chains
+}
+
+func (se *SwapEstimator) SetAllowNodeOverride(allow bool) {
+	se.allowNodeOverride = allow
+}
+
+func (se *SwapEstimator) SetMaxSrcAmount(maxSrcAmount *big.Int) {
+	se.maxSrcAmount = maxSrcAmount
+}
+
+// forChain returns an estimator that shares this one's settings but talks to
+// the node for chainIDStr. An empty chainIDStr selects the default node.
+func (se *SwapEstimator) forChain(chainIDStr string) (*SwapEstimator, error) {
+	if chainIDStr == "" {
+		return se, nil
+	}
 
-	reserves, err := se.ethClient.GetReserves(ctx, poolAddr)
+	chainID, err := strconv.ParseUint(chainIDStr, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get reserves: %w", err)
+		return nil, fmt.Errorf("%w: %q", ErrChainNotConfigured, chainIDStr)
+	}
+
+	if se.chains == nil {
+		return nil, fmt.Errorf("%w: %d", ErrChainNotConfigured, chainID)
 	}
 
-	token0, err := se.ethClient.GetToken0(ctx, poolAddr)
+	client, err := se.chains.Get(chainID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get token0: %w", err)
+		return nil, err
 	}
 
-	var reserveIn, reserveOut *big.Int
+	chainEstimator := *se
+	chainEstimator.ethClient = client
+	// The mainnet contract defaults don't exist on other chains, so only
+	// addresses configured for this chain are used
+	contracts := se.chains.Contracts(chainID)
+	chainEstimator.weth = contracts.WETH
+	chainEstimator.factory = contracts.Factory
+	chainEstimator.router = contracts.Router
+	chainEstimator.quoterV3 = contracts.QuoterV3
+	// USD_PRICE_POOL is an address on the default chain
+	chainEstimator.usdPricePool = common.Address{}
+	return &chainEstimator, nil
+}
 
-	if token0 == srcToken {
-		reserveIn = reserves.Reserve0
-		reserveOut = reserves.Reserve1
-	} else if token0 == dstToken {
-		reserveIn = reserves.Reserve1
-		reserveOut = reserves.Reserve0
-	} else {
-		return nil, fmt.Errorf("token addresses don't match pool tokens")
+// withRPCTimeout bounds the node calls made on behalf of a single request.
+func (se *SwapEstimator) withRPCTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, se.rpcTimeout)
+}
+
+func (se *SwapEstimator) EstimateSwap(ctx context.Context, poolAddr, srcToken, dstToken common.Address, srcAmount *big.Int) (*SwapEstimate, error) {
+	return se.EstimateSwapWithOptions(ctx, poolAddr, srcToken, dstToken, srcAmount, se.DefaultOptions())
+}
+
+func (se *SwapEstimator) EstimateSwapWithOptions(ctx context.Context, poolAddr, srcToken, dstToken common.Address, srcAmount *big.Int, opts EstimateOptions) (*SwapEstimate, error) {
+
+	state := opts.PoolState
+	if state == nil {
+		var err error
+		state, err = se.GetPoolState(ctx, poolAddr, opts.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reserves, err := orientReserves(poolAddr, state, srcToken, dstToken)
+	if err != nil {
+		return nil, err
+	}
+
+	amountIn := srcAmount
+	if opts.TransferFeeBps > 0 {
+		amountIn = applyTransferFee(srcAmount, opts.TransferFeeBps)
+	}
+
+	curve := opts.Curve
+	if curve == nil {
+		curve = constantProductCurve{}
+	}
+	curveReserves := CurveReserves{In: reserves.ReserveIn, Out: reserves.ReserveOut}
+	curveParams := CurveParams{Fee: opts.Fee, Amplification: opts.Amplification}
+
+	amountOut := curve.ComputeOut(amountIn, curveReserves, curveParams)
+	if err := checkAmountOut(amountOut, reserves.ReserveOut); err != nil {
+		return nil, fmt.Errorf("pool %s: %w", poolAddr.Hex(), err)
+	}
+
+	return &SwapEstimate{
+		AmountIn:   amountIn,
+		AmountOut:  amountOut,
+		ReserveIn:  reserves.ReserveIn,
+		ReserveOut: reserves.ReserveOut,
+		// Measured against srcAmount so the transfer fee shows up as impact
+		PriceImpact:        curvePriceImpact(curve, srcAmount, amountOut, curveReserves, curveParams),
+		FeeAmount:          calculateFeeAmount(amountIn, opts.Fee),
+		K:                  reserves.K(),
+		BlockTimestampLast: reserves.BlockTimestampLast,
+		Token0:             reserves.Token0,
+		Token1:             reserves.Token1,
+		ZeroForOne:         reserves.ZeroForOne,
+	}, nil
+}
+
+func (se *SwapEstimator) EstimateSwapForExactOutput(ctx context.Context, poolAddr, srcToken, dstToken common.Address, dstAmount *big.Int) (*big.Int, error) {
+
+	reserves, err := se.getDirectionalReserves(ctx, poolAddr, srcToken, dstToken, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if dstAmount.Cmp(reserves.ReserveOut) >= 0 {
+		return nil, fmt.Errorf("%w: requested output %s exceeds available reserve %s", ErrInsufficientLiquidity, dstAmount, reserves.ReserveOut)
+	}
+
+	return calculateSwapAmountIn(dstAmount, reserves.ReserveIn, reserves.ReserveOut, se.fee)
+}
+
+// EstimateMultiHop returns the amounts at each step of the route, starting with
+// srcAmount and ending with the final output, like Router02.getAmountsOut.
+func (se *SwapEstimator) EstimateMultiHop(ctx context.Context, path []common.Address, pools []common.Address, srcAmount *big.Int) ([]*big.Int, error) {
+	if len(path) < 2 {
+		return nil, fmt.Errorf("path must contain at least two tokens")
+	}
+
+	if len(pools) != len(path)-1 {
+		return nil, fmt.Errorf("expected %d pools for a path of %d tokens, got %d", len(path)-1, len(path), len(pools))
+	}
+
+	amounts := make([]*big.Int, len(path))
+	amounts[0] = srcAmount
+
+	for i, pool := range pools {
+		reserves, err := se.getDirectionalReserves(ctx, pool, path[i], path[i+1], nil)
+		if err != nil {
+			return nil, fmt.Errorf("hop %d (%s): %w", i, pool.Hex(), err)
+		}
+
+		amounts[i+1] = calculateSwapAmount(amounts[i], reserves.ReserveIn, reserves.ReserveOut, se.fee)
+		if err := checkAmountOut(amounts[i+1], reserves.ReserveOut); err != nil {
+			return nil, fmt.Errorf("hop %d (%s): %w", i, pool.Hex(), err)
+		}
+	}
+
+	return amounts, nil
+}
+
+// GetPoolState reads a pair's reserves and tokens at the given block, or the
+// latest block when blockNumber is nil.
+func (se *SwapEstimator) GetPoolState(ctx context.Context, poolAddr common.Address, blockNumber *big.Int) (*PoolState, error) {
+	if err := se.checkPoolAllowed(poolAddr); err != nil {
+		return nil, err
+	}
+
+	// The tokens are read first: they're usually cached, and a failure then
+	// doesn't waste a reserves read
+	token0, err := se.ethClient.GetToken0(ctx, poolAddr, blockNumber)
+	if err != nil {
+		return nil, pairCallError(poolAddr, "token0", err)
+	}
+
+	token1, err := se.ethClient.GetToken1(ctx, poolAddr, blockNumber)
+	if err != nil {
+		return nil, pairCallError(poolAddr, "token1", err)
+	}
+
+	if token0 == (common.Address{}) || token1 == (common.Address{}) {
+		return nil, fmt.Errorf("%w: %s reports a zero token address", ErrNotUniswapV2Pair, poolAddr.Hex())
+	}
+
+	reserves, err := se.ethClient.GetReserves(ctx, poolAddr, blockNumber)
+	if err != nil {
+		return nil, pairCallError(poolAddr, "reserves", err)
 	}
 
-	return calculateSwapAmount(srcAmount, reserveIn, reserveOut), nil
+	return &PoolState{PoolReserves: reserves, Token0: token0, Token1: token1}, nil
+}
+
+func (se *SwapEstimator) getDirectionalReserves(ctx context.Context, poolAddr, srcToken, dstToken common.Address, blockNumber *big.Int) (*directionalReserves, error) {
+
+	state, err := se.GetPoolState(ctx, poolAddr, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return orientReserves(poolAddr, state, srcToken, dstToken)
 }
 
-func calculateSwapAmount(amountIn, reserveIn, reserveOut *big.Int) *big.Int {
+// orientReserves orders state's reserves for a srcToken to dstToken swap.
+func orientReserves(poolAddr common.Address, state *PoolState, srcToken, dstToken common.Address) (*directionalReserves, error) {
+	if state.Reserve0.Sign() == 0 || state.Reserve1.Sign() == 0 {
+		return nil, fmt.Errorf("%w: %s has reserves %s/%s", ErrNoLiquidity, poolAddr.Hex(), state.Reserve0, state.Reserve1)
+	}
 
-	amountInWithFee := new(big.Int).Mul(amountIn, big.NewInt(997))
-	numerator := new(big.Int).Mul(amountInWithFee, reserveOut)
+	directional := &directionalReserves{PoolState: state}
+	if srcToken == state.Token0 && dstToken == state.Token1 {
+		directional.ZeroForOne = true
+		directional.ReserveIn, directional.ReserveOut = state.Reserve0, state.Reserve1
+		return directional, nil
+	} else if srcToken == state.Token1 && dstToken == state.Token0 {
+		directional.ReserveIn, directional.ReserveOut = state.Reserve1, state.Reserve0
+		return directional, nil
+	}
 
-	denominator := new(big.Int).Mul(reserveIn, big.NewInt(1000))
-	denominator.Add(denominator, amountInWithFee)
+	return nil, fmt.Errorf("%w: token addresses %s/%s don't match pool tokens %s/%s", ErrTokenMismatch, srcToken.Hex(), dstToken.Hex(), state.Token0.Hex(), state.Token1.Hex())
+}
 
-	amountOut := new(big.Int).Div(numerator, denominator)
+// pairCallError distinguishes a contract that doesn't implement the pair
+// interface from a failure to reach the node.
+func pairCallError(poolAddr common.Address, field string, err error) error {
+	if errors.Is(err, errEmptyResult) {
+		return fmt.Errorf("%w: no contract code at %s", ErrPoolNotFound, poolAddr.Hex())
+	}
+	if isContractFailure(err) {
+		return fmt.Errorf("%w: %s failed to return %s: %v", ErrNotUniswapV2Pair, poolAddr.Hex(), field, err)
+	}
+	if errors.Is(err, ErrStateUnavailable) || isTimeout(err) {
+		return fmt.Errorf("failed to get %s: %w", field, err)
+	}
+	return fmt.Errorf("%w: failed to get %s: %w", ErrRPCFailure, field, err)
+}
+
+// reservesAge returns how long before blockTimestamp the reserves were last
+// updated, clamping to zero if the reserves were read from a newer block.
+func reservesAge(blockTimestamp uint64, blockTimestampLast uint32) *uint64 {
+	age := uint64(0)
+	if last := uint64(blockTimestampLast); blockTimestamp > last {
+		age = blockTimestamp - last
+	}
+	return &age
+}
+
+// swapScratch holds calculateSwapAmount's intermediates, pooled so the hot
+// path only allocates the result.
+type swapScratch struct {
+	factor, amountInWithFee, numerator, denominator, remainder big.Int
+}
+
+var swapScratchPool = sync.Pool{New: func() any { return new(swapScratch) }}
+
+func calculateSwapAmount(amountIn, reserveIn, reserveOut *big.Int, fee SwapFee) *big.Int {
+	// Nothing can be bought from an empty pool, which also keeps the
+	// denominator non-zero
+	if amountIn.Sign() <= 0 || reserveIn.Sign() <= 0 || reserveOut.Sign() <= 0 {
+		return new(big.Int)
+	}
+
+	s := swapScratchPool.Get().(*swapScratch)
+	defer swapScratchPool.Put(s)
+
+	s.factor.SetInt64(fee.Numerator)
+	s.amountInWithFee.Mul(amountIn, &s.factor)
+	s.numerator.Mul(&s.amountInWithFee, reserveOut)
+
+	s.factor.SetInt64(fee.Denominator)
+	s.denominator.Mul(reserveIn, &s.factor)
+	s.denominator.Add(&s.denominator, &s.amountInWithFee)
+
+	// Both operands are non-negative, so truncated division matches Div
+	amountOut, _ := new(big.Int).QuoRem(&s.numerator, &s.denominator, &s.remainder)
 	return amountOut
 }
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
+// calculatePriceImpact returns how far the execution price (amountOut/amountIn)
+// falls below the spot price (reserveOut/reserveIn), as a percentage.
+// Both prices are dst base units per src base unit, so token decimals cancel
+// and the raw amounts need no normalizing, even for pairs like USDC/WETH.
+func calculatePriceImpact(amountIn, amountOut, reserveIn, reserveOut *big.Int) *big.Rat {
+	if amountIn.Sign() == 0 || reserveOut.Sign() == 0 {
+		return new(big.Rat)
+	}
+
+	// 1 - (amountOut/amountIn) / (reserveOut/reserveIn)
+	ratio := new(big.Rat).SetFrac(
+		new(big.Int).Mul(amountOut, reserveIn),
+		new(big.Int).Mul(amountIn, reserveOut),
+	)
+
+	impact := new(big.Rat).Sub(big.NewRat(1, 1), ratio)
+	return impact.Mul(impact, big.NewRat(100, 1))
+}
+
+// checkAmountOut asserts that a swap leaves part of reserveOut in the pool,
+// which the constant-product formula guarantees for any input.
+func checkAmountOut(amountOut, reserveOut *big.Int) error {
+	if amountOut.Sign() < 0 || amountOut.Cmp(reserveOut) >= 0 {
+		return fmt.Errorf("%w: output %s is not below reserve %s", errImpossibleQuote, amountOut, reserveOut)
+	}
+	return nil
+}
+
+// calculateFeeAmount returns the LP fee taken from amountIn, e.g.
+// amountIn * 3 / 1000 for the default 0.3% fee.
+func calculateFeeAmount(amountIn *big.Int, fee SwapFee) *big.Int {
+	feeAmount := new(big.Int).Mul(amountIn, big.NewInt(fee.Denominator-fee.Numerator))
+	return feeAmount.Div(feeAmount, big.NewInt(fee.Denominator))
+}
+
+// calculateMinAmountOut applies a slippage tolerance to an estimated output,
+// rounding down so the minimum is never above what the tolerance allows.
+func calculateMinAmountOut(amountOut *big.Int, slippageBps int64) *big.Int {
+	minAmountOut := new(big.Int).Mul(amountOut, big.NewInt(10000-slippageBps))
+	return minAmountOut.Div(minAmountOut, big.NewInt(10000))
+}
+
+// splitIntegratorFee divides a pool's output into the integrator's fee of
+// feeBps, rounded down, and the net amount left for the user.
+func splitIntegratorFee(amountOut *big.Int, feeBps int64) (*big.Int, *big.Int) {
+	fee := new(big.Int).Mul(amountOut, big.NewInt(feeBps))
+	fee.Div(fee, big.NewInt(10000))
+	return fee, new(big.Int).Sub(amountOut, fee)
+}
+
+// calculateSwapAmountIn mirrors UniswapV2Library.getAmountIn, rounding up so
+// the returned input is always sufficient to receive amountOut. A 100% fee
+// leaves nothing for the pool, so no input buys any output.
+func calculateSwapAmountIn(amountOut, reserveIn, reserveOut *big.Int, fee SwapFee) (*big.Int, error) {
+	if fee.Numerator <= 0 {
+		return nil, fmt.Errorf("fee of %d/%d leaves no input for the pool", fee.Denominator-fee.Numerator, fee.Denominator)
+	}
+
+	numerator := new(big.Int).Mul(reserveIn, amountOut)
+	numerator.Mul(numerator, big.NewInt(fee.Denominator))
+
+	denominator := new(big.Int).Sub(reserveOut, amountOut)
+	denominator.Mul(denominator, big.NewInt(fee.Numerator))
+
+	amountIn := new(big.Int).Div(numerator, denominator)
+	return amountIn.Add(amountIn, big.NewInt(1)), nil
+}
+
+// liveHandler reports that the process is serving requests without touching
+// the node.
+func liveHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+// healthHandler reports whether the default node is reachable, so load
+// balancers stop routing to an instance that can't produce estimates.
+func (se *SwapEstimator) healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	blockNumber, err := se.ethClient.BlockNumber(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "health check failed", "error", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(HealthResponse{Status: "unavailable", Error: "Ethereum node unreachable"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(HealthResponse{Status: "ok", BlockNumber: blockNumber})
+}
 
 func (se *SwapEstimator) estimateHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	query := r.URL.Query()
+	req := EstimateRequest{
+		Pool:      query.Get("pool"),
+		Src:       query.Get("src"),
+		Dst:       query.Get("dst"),
+		SrcAmount: query.Get("src_amount"),
+		FeeBps:    query.Get("fee_bps"),
+		Block:     query.Get("block"),
+		BlockTag:  query.Get("block_tag"),
+		Format:    query.Get("format"),
+		Precision: query.Get("precision"),
+		ChainID:   query.Get("chain_id"),
+
+		SlippageBps:      query.Get("slippage_bps"),
+		IntegratorFeeBps: query.Get("integrator_fee_bps"),
+		IncludeState:     query.Get("include_state"),
+		NodeURL:          query.Get("node_url"),
+
+		IncludeMetadata: query.Get("include_metadata"),
+		IncludeGas:      query.Get("include_gas"),
+		IncludeRate:     query.Get("include_rate"),
+		Envelope:        query.Get("envelope"),
+		Revalidate:      query.Get("revalidate"),
+		Owner:           query.Get("owner"),
+		NumberFormat:    query.Get("number_format"),
+
+		IncludeLiquidityUSD: query.Get("include_liquidity_usd"),
+
+		TransferFeeBps:   query.Get("transfer_fee_bps"),
+		CheckTransferFee: query.Get("check_transfer_fee"),
+		CheckRebasing:    query.Get("check_rebasing"),
+
+		Engine:        query.Get("engine"),
+		Curve:         query.Get("curve"),
+		Amplification: query.Get("amplification"),
+		Debug:         query.Get("debug"),
+	}
+
+	se.serveEstimate(w, r, req)
+}
+
+func (se *SwapEstimator) estimatePostHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req EstimateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "Invalid JSON body")
+		return
+	}
+
+	se.serveEstimate(w, r, req)
+}
+
+// estimateParams is a validated EstimateRequest bound to the estimator for
+// the requested chain.
+type estimateParams struct {
+	estimator *SwapEstimator
+	pool      common.Address
+	tokens    swapTokens
+	srcAmount *big.Int
+	format    string
+	precision int
+	opts      EstimateOptions
+	// slippageBps is nil when the request didn't ask for min_dst_amount
+	slippageBps *int64
+	// integratorFeeBps is nil when the request didn't ask for net_dst_amount
+	integratorFeeBps *int64
+	includeState     bool
+	includeGas       bool
+	includeRate      bool
+	envelope         bool
+	revalidate       bool
+	numberAmounts    bool
+	// includeLiquidityUSD needs USD_PRICE_POOL on the request's chain
+	includeLiquidityUSD bool
+	// inferDst is set until dst, left out of the request, is read from the
+	// pool
+	inferDst bool
+	// owner is nil unless the request asks for an allowance check
+	owner            *common.Address
+	includeMetadata  bool
+	checkTransferFee bool
+	checkRebasing    bool
+	// useRouter replaces the local dst_amount with the router's quote
+	useRouter bool
+	// debugCalldata skips the estimate and returns the packed pair calls
+	debugCalldata bool
+	// nodeClient is the transient client dialed for node_url, if any
+	nodeClient *EthereumClient
+}
+
+// Close releases the transient node_url client, if one was dialed.
+func (p *estimateParams) Close() {
+	if p.nodeClient != nil {
+		p.nodeClient.Close()
+	}
+}
+
+// parseAmount parses a base-unit token amount, rejecting zero and negative
+// values so they can't produce a misleading zero quote.
+func parseAmount(field, s string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, withCode(CodeInvalidAmount, fmt.Errorf("Invalid %s format", field))
+	}
+	if amount.Sign() <= 0 {
+		return nil, withCode(CodeInvalidAmount, fmt.Errorf("Invalid %s: must be greater than zero", field))
+	}
+	return amount, nil
+}
+
+// parseSrcAmount parses src_amount and enforces the configured upper bound,
+// which stops callers from burning CPU on absurdly large inputs.
+func (se *SwapEstimator) parseSrcAmount(s string) (*big.Int, error) {
+	return se.parseInputAmount("src_amount", s)
+}
+
+// parseInputAmount parses field, an amount swapped into the pool, with the
+// same bound as src_amount.
+func (se *SwapEstimator) parseInputAmount(field, s string) (*big.Int, error) {
+	amount, err := parseAmount(field, s)
+	if err != nil {
+		return nil, err
+	}
+	if se.maxSrcAmount != nil && amount.Cmp(se.maxSrcAmount) > 0 {
+		return nil, withCode(CodeInvalidAmount, fmt.Errorf("Invalid %s: must not exceed %s", field, se.maxSrcAmount))
+	}
+	return amount, nil
+}
+
+// parseEstimateRequest validates req and returns the HTTP status to report
+// when it is rejected.
+func (se *SwapEstimator) parseEstimateRequest(req EstimateRequest) (*estimateParams, int, error) {
+	if req.Pool == "" || req.Src == "" || req.SrcAmount == "" {
+		return nil, http.StatusBadRequest, withCode(CodeMissingParameter, errors.New("Missing required parameters: pool, src, src_amount"))
+	}
+
+	se, err := se.forChain(req.ChainID)
+	if errors.Is(err, ErrChainNotConfigured) {
+		return nil, http.StatusBadRequest, withCode(CodeChainNotConfigured, fmt.Errorf("Chain %s is not configured", req.ChainID))
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("Failed to connect to chain: %w", err)
+	}
+
+	poolAddr, err := parseAddress("pool", req.Pool)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	tokens, err := se.resolveSwapTokens(req.Src, req.Dst)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	srcAmount, err := se.parseSrcAmount(req.SrcAmount)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if req.Format != "" && req.Format != "raw" && req.Format != "decimal" {
+		return nil, http.StatusBadRequest, errors.New("Invalid format: must be raw or decimal")
+	}
+
+	precision := defaultDecimalPrecision
+	if req.Precision != "" {
+		precision, err = strconv.Atoi(req.Precision)
+		if err != nil || precision < 0 || precision > 255 {
+			return nil, http.StatusBadRequest, errors.New("Invalid precision: must be an integer between 0 and 255")
+		}
+	}
+
+	opts := se.DefaultOptions()
+	if req.FeeBps != "" {
+		feeBps, err := strconv.ParseInt(req.FeeBps, 10, 64)
+		if err != nil || feeBps < 0 || feeBps > 10000 {
+			return nil, http.StatusBadRequest, errors.New("Invalid fee_bps: must be an integer between 0 and 10000")
+		}
+		opts.Fee = SwapFeeFromBps(feeBps)
+	}
+
+	opts.BlockNumber, err = parseBlockParams(req.Block, req.BlockTag)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	params := &estimateParams{
+		estimator: se,
+		pool:      poolAddr,
+		tokens:    tokens,
+		srcAmount: srcAmount,
+		format:    req.Format,
+		precision: precision,
+		opts:      opts,
+		inferDst:  req.Dst == "",
+	}
+
+	if req.SlippageBps != "" {
+		slippageBps, err := strconv.ParseInt(req.SlippageBps, 10, 64)
+		if err != nil || slippageBps < 0 || slippageBps > 10000 {
+			return nil, http.StatusBadRequest, errors.New("Invalid slippage_bps: must be an integer between 0 and 10000")
+		}
+		params.slippageBps = &slippageBps
+	}
+
+	if req.IntegratorFeeBps != "" {
+		integratorFeeBps, err := strconv.ParseInt(req.IntegratorFeeBps, 10, 64)
+		if err != nil || integratorFeeBps < 0 || integratorFeeBps > 10000 {
+			return nil, http.StatusBadRequest, errors.New("Invalid integrator_fee_bps: must be an integer between 0 and 10000")
+		}
+		params.integratorFeeBps = &integratorFeeBps
+	}
+
+	if req.IncludeState != "" {
+		includeState, err := strconv.ParseBool(req.IncludeState)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid include_state: must be true or false")
+		}
+		params.includeState = includeState
+	}
+
+	if req.IncludeGas != "" {
+		includeGas, err := strconv.ParseBool(req.IncludeGas)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid include_gas: must be true or false")
+		}
+		params.includeGas = includeGas
+	}
+
+	if req.IncludeRate != "" {
+		includeRate, err := strconv.ParseBool(req.IncludeRate)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid include_rate: must be true or false")
+		}
+		params.includeRate = includeRate
+	}
+
+	if req.IncludeLiquidityUSD != "" {
+		includeLiquidityUSD, err := strconv.ParseBool(req.IncludeLiquidityUSD)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid include_liquidity_usd: must be true or false")
+		}
+		if includeLiquidityUSD && se.usdPricePool == (common.Address{}) {
+			return nil, http.StatusBadRequest, errors.New("Invalid include_liquidity_usd: USD_PRICE_POOL is not configured for this chain")
+		}
+		params.includeLiquidityUSD = includeLiquidityUSD
+	}
+
+	switch req.NumberFormat {
+	case "", numberFormatString:
+	case numberFormatNumber:
+		params.numberAmounts = true
+	default:
+		return nil, http.StatusBadRequest, errors.New("Invalid number_format: must be string or number")
+	}
+
+	if req.Owner != "" {
+		owner, err := parseAddress("owner", req.Owner)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		// The allowance is checked against the router
+		if se.router == (common.Address{}) {
+			return nil, http.StatusBadRequest, errors.New("owner is not supported on this chain: no router address configured")
+		}
+		params.owner = &owner
+	}
+
+	if req.Envelope != "" {
+		envelope, err := strconv.ParseBool(req.Envelope)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid envelope: must be true or false")
+		}
+		params.envelope = envelope
+	}
+
+	if req.Revalidate != "" {
+		revalidate, err := strconv.ParseBool(req.Revalidate)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid revalidate: must be true or false")
+		}
+		params.revalidate = revalidate
+	}
+
+	if req.IncludeMetadata != "" {
+		includeMetadata, err := strconv.ParseBool(req.IncludeMetadata)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid include_metadata: must be true or false")
+		}
+		params.includeMetadata = includeMetadata
+	}
+
+	if req.TransferFeeBps != "" {
+		transferFeeBps, err := strconv.ParseInt(req.TransferFeeBps, 10, 64)
+		if err != nil || transferFeeBps < 0 || transferFeeBps >= 10000 {
+			return nil, http.StatusBadRequest, errors.New("Invalid transfer_fee_bps: must be an integer between 0 and 9999")
+		}
+		params.opts.TransferFeeBps = transferFeeBps
+	}
+
+	if req.CheckTransferFee != "" {
+		checkTransferFee, err := strconv.ParseBool(req.CheckTransferFee)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid check_transfer_fee: must be true or false")
+		}
+		params.checkTransferFee = checkTransferFee
+	}
+
+	if req.CheckRebasing != "" {
+		checkRebasing, err := strconv.ParseBool(req.CheckRebasing)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid check_rebasing: must be true or false")
+		}
+		params.checkRebasing = checkRebasing
+	}
+
+	switch req.Engine {
+	case "", engineLocal:
+	case engineRouter:
+		if se.router == (common.Address{}) {
+			return nil, http.StatusBadRequest, errors.New("engine=router is not supported on this chain: no router address configured")
+		}
+		params.useRouter = true
+	default:
+		return nil, http.StatusBadRequest, errors.New("Invalid engine: must be local or router")
+	}
+
+	if req.Curve != "" {
+		curve, ok := curves[req.Curve]
+		if !ok {
+			return nil, http.StatusBadRequest, errors.New("Invalid curve: must be constant_product or stableswap")
+		}
+		if params.useRouter && req.Curve != curveConstantProduct {
+			return nil, http.StatusBadRequest, errors.New("Invalid curve: engine=router only supports constant_product")
+		}
+		params.opts.Curve = curve
+	}
+
+	if req.Curve == curveStableSwap {
+		params.opts.Amplification = defaultAmplification
+	}
+	if req.Amplification != "" {
+		if req.Curve != curveStableSwap {
+			return nil, http.StatusBadRequest, errors.New("Invalid amplification: only applies to curve=stableswap")
+		}
+		amplification, err := strconv.ParseInt(req.Amplification, 10, 64)
+		if err != nil || amplification < 1 || amplification > maxAmplification {
+			return nil, http.StatusBadRequest, fmt.Errorf("Invalid amplification: must be an integer between 1 and %d", maxAmplification)
+		}
+		params.opts.Amplification = amplification
+	}
+
+	if req.Debug != "" {
+		if req.Debug != "calldata" {
+			return nil, http.StatusBadRequest, errors.New("Invalid debug: must be calldata")
+		}
+		params.debugCalldata = true
+	}
+
+	// Dialed last so a rejected request never leaves a client open
+	if req.NodeURL != "" && se.allowNodeOverride {
+		client, err := NewEthereumClient(req.NodeURL)
+		if err != nil {
+			return nil, http.StatusBadRequest, errors.New("Invalid node_url: failed to connect")
+		}
+
+		overridden := *se
+		overridden.ethClient = client
+		params.estimator = &overridden
+		params.nodeClient = client
+	}
+
+	return params, http.StatusOK, nil
+}
+
+func (se *SwapEstimator) serveEstimate(w http.ResponseWriter, r *http.Request, req EstimateRequest) {
+	start := time.Now()
+	outcome := "invalid_request"
+	var estimateErr error
+	defer func() {
+		logEstimateRequest(r.Context(), req, start, outcome, estimateErr, se.slowRequestThreshold)
+	}()
+
+	// An invalid revalidate is rejected by parseEstimateRequest below
+	if revalidate, _ := strconv.ParseBool(req.Revalidate); !revalidate {
+		if body, ok := se.responseCache.get(req); ok {
+			outcome = "cached"
+			w.Write(body)
+			return
+		}
+	}
+
+	params, status, err := se.parseEstimateRequest(req)
+	if err != nil {
+		if status >= http.StatusInternalServerError {
+			outcome, estimateErr = "error", err
+			err = errors.New("Failed to connect to chain")
+		}
+		writeRequestError(w, status, err)
+		return
+	}
+	defer params.Close()
+	se = params.estimator
+
+	if params.debugCalldata {
+		calls, err := se.ethClient.PairCalldata(params.pool)
+		if err != nil {
+			outcome, estimateErr = "error", err
+			writeError(w, http.StatusInternalServerError, CodeInternalError, "Failed to encode calldata")
+			return
+		}
+		response := CalldataResponse{Calls: calls}
+		if params.opts.BlockNumber != nil {
+			response.Block = formatBlock(params.opts.BlockNumber)
+		}
+		outcome = "dry_run"
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	// Pinning the block also bypasses the reserves cache, which only serves
+	// reads of the default block
+	var meta *ResponseMeta
+	if params.envelope || params.revalidate {
+		meta, err = se.pinQuoteBlock(ctx, &params.opts)
+		if err != nil {
+			outcome, estimateErr = "error", err
+			writeEstimateError(w, err)
+			return
+		}
+	}
+
+	opts := params.opts
+	var inferredDst string
+	if params.inferDst {
+		opts.PoolState, err = se.inferDst(ctx, params)
+		if err != nil {
+			outcome, estimateErr = "error", err
+			writeEstimateError(w, err)
+			return
+		}
+		inferredDst = params.tokens.Dst.Hex()
+	}
+
+	estimate, err := se.EstimateSwapWithOptions(ctx, params.pool, params.tokens.Src, params.tokens.Dst, params.srcAmount, opts)
+	if err != nil {
+		outcome, estimateErr = "error", err
+		writeEstimateError(w, err)
+		return
+	}
+
+	if params.useRouter {
+		// The router quotes what reaches the pool, after any transfer fee
+		estimate.AmountOut, err = se.EstimateSwapViaRouter(ctx, params.tokens.Src, params.tokens.Dst, estimate.AmountIn, params.opts.BlockNumber)
+		if err != nil {
+			outcome, estimateErr = "error", err
+			writeEstimateError(w, err)
+			return
+		}
+	}
+
+	response := EstimateResponse{
+		Dst:         inferredDst,
+		DstAmount:   estimate.AmountOut.String(),
+		PriceImpact: estimate.PriceImpact.FloatString(4),
+		FeeAmount:   estimate.FeeAmount.String(),
+		WrapsETH:    params.tokens.WrapSrc,
+		UnwrapsWETH: params.tokens.UnwrapDst,
+		Token0:      estimate.Token0.Hex(),
+		Token1:      estimate.Token1.Hex(),
+		ZeroForOne:  estimate.ZeroForOne,
+
+		numberAmounts: params.numberAmounts,
+	}
+
+	var minAmountOut *big.Int
+	if params.slippageBps != nil {
+		minAmountOut = calculateMinAmountOut(estimate.AmountOut, *params.slippageBps)
+		response.MinDstAmount = minAmountOut.String()
+	}
+
+	var integratorFee, netAmountOut *big.Int
+	if params.integratorFeeBps != nil {
+		integratorFee, netAmountOut = splitIntegratorFee(estimate.AmountOut, *params.integratorFeeBps)
+		response.IntegratorFeeAmount = integratorFee.String()
+		response.NetDstAmount = netAmountOut.String()
+	}
+
+	if params.includeState {
+		blockTimestamp, err := se.ethClient.GetBlockTimestamp(ctx, params.opts.BlockNumber)
+		if err != nil {
+			outcome, estimateErr = "error", err
+			writeEstimateError(w, err)
+			return
+		}
+		response.K = estimate.K.String()
+		response.BlockTimestampLast = estimate.BlockTimestampLast
+		response.ReservesAgeSeconds = reservesAge(blockTimestamp, estimate.BlockTimestampLast)
+	}
+
+	if params.includeGas {
+		response.Gas, err = se.EstimateGas(ctx)
+		if errors.Is(err, errNoBaseFee) {
+			// The quote itself is fine; only the EIP-1559 pricing is missing
+			response.Warnings = append(response.Warnings, "Could not price gas: the chain has no EIP-1559 base fee")
+		} else if err != nil {
+			outcome, estimateErr = "error", err
+			writeEstimateError(w, err)
+			return
+		}
+	}
+
+	if params.includeRate {
+		response.Rate = exchangeRate(params.srcAmount, estimate.AmountOut)
+	}
+
+	if params.includeLiquidityUSD {
+		se.setLiquidityUSD(ctx, params, estimate, &response)
+	}
+
+	if params.revalidate {
+		response.BlockNumber = meta.BlockNumber
+	}
+
+	if params.checkTransferFee {
+		response.Warnings = append(response.Warnings, se.transferFeeWarnings(ctx, params, estimate)...)
+	}
+
+	if params.checkRebasing {
+		se.checkRebasing(ctx, params, &response)
+	}
+
+	if params.owner != nil {
+		se.checkApproval(ctx, params, &response)
+	}
+
+	if params.includeMetadata {
+		response.SrcSymbol, response.DstSymbol = se.tokenSymbols(ctx, params.tokens)
+	}
+
+	if params.format == "decimal" {
+		srcDecimals, dstDecimals, err := se.ethClient.GetPairDecimals(ctx, params.tokens.Src, params.tokens.Dst)
+		if err != nil {
+			outcome, estimateErr = "error", fmt.Errorf("failed to fetch decimals: %w", err)
+			writeError(w, http.StatusInternalServerError, CodeInternalError, "Failed to fetch token decimals")
+			return
+		}
+		response.DstAmount = formatUnits(estimate.AmountOut, dstDecimals, params.precision)
+		response.FeeAmount = formatUnits(estimate.FeeAmount, srcDecimals, params.precision)
+		if minAmountOut != nil {
+			response.MinDstAmount = formatUnits(minAmountOut, dstDecimals, params.precision)
+		}
+		if netAmountOut != nil {
+			response.IntegratorFeeAmount = formatUnits(integratorFee, dstDecimals, params.precision)
+			response.NetDstAmount = formatUnits(netAmountOut, dstDecimals, params.precision)
+		}
+	}
+
+	var payload any = response
+	if params.envelope {
+		meta.Timestamp = time.Now().Unix()
+		payload = ResponseEnvelope{Data: response, Meta: *meta}
+	}
+
+	outcome = "success"
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(payload)
+	if !params.revalidate {
+		se.responseCache.put(req, body.Bytes())
+	}
+	w.Write(body.Bytes())
+}
+
+func (se *SwapEstimator) estimateExactOutHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
 	poolStr := r.URL.Query().Get("pool")
 	srcStr := r.URL.Query().Get("src")
 	dstStr := r.URL.Query().Get("dst")
+	dstAmountStr := r.URL.Query().Get("dst_amount")
+
+	if poolStr == "" || srcStr == "" || dstStr == "" || dstAmountStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameters: pool, src, dst, dst_amount")
+		return
+	}
+
+	poolAddr, err := parseAddress("pool", poolStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	srcAddr, err := parseAddress("src", srcStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	dstAddr, err := parseAddress("dst", dstStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if srcAddr == dstAddr {
+		writeRequestError(w, http.StatusBadRequest, errSameToken)
+		return
+	}
+
+	dstAmount, err := parseAmount("dst_amount", dstAmountStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	srcAmount, err := se.EstimateSwapForExactOutput(ctx, poolAddr, srcAddr, dstAddr, dstAmount)
+	if err != nil {
+		slog.WarnContext(ctx, "exact output estimate failed", "pool", poolStr, "src", srcStr, "dst", dstStr, "dst_amount", dstAmountStr, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	response := EstimateExactOutResponse{
+		SrcAmount: srcAmount.String(),
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (se *SwapEstimator) estimateRouteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pathStr := r.URL.Query().Get("path")
+	poolsStr := r.URL.Query().Get("pools")
 	srcAmountStr := r.URL.Query().Get("src_amount")
 
-	if poolStr == "" || srcStr == "" || dstStr == "" || srcAmountStr == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Missing required parameters: pool, src, dst, src_amount"})
+	if pathStr == "" || poolsStr == "" || srcAmountStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameters: path, pools, src_amount")
 		return
 	}
 
-	poolAddr := common.HexToAddress(poolStr)
-	srcAddr := common.HexToAddress(srcStr)
-	dstAddr := common.HexToAddress(dstStr)
+	path, err := parseAddressList("path", pathStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
 
-	srcAmount, ok := new(big.Int).SetString(srcAmountStr, 10)
-	if !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Invalid src_amount format"})
+	pools, err := parseAddressList("pools", poolsStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(path) < 2 || len(pools) != len(path)-1 {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "Invalid route: pools must contain exactly one address per hop in path")
 		return
 	}
 
-	dstAmount, err := se.EstimateSwap(r.Context(), poolAddr, srcAddr, dstAddr, srcAmount)
+	for i := 1; i < len(path); i++ {
+		if path[i] == path[i-1] {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("Invalid route: hop %d swaps %s for itself", i-1, path[i].Hex()))
+			return
+		}
+	}
+
+	srcAmount, err := se.parseSrcAmount(srcAmountStr)
 	if err != nil {
-		log.Printf("Error estimating swap: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Error: "Failed to estimate swap"})
+		writeRequestError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	response := EstimateResponse{
-		DstAmount: dstAmount.String(),
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	amounts, err := se.EstimateMultiHop(ctx, path, pools, srcAmount)
+	if err != nil {
+		slog.WarnContext(ctx, "route estimate failed", "path", pathStr, "pools", poolsStr, "src_amount", srcAmountStr, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	response := EstimateRouteResponse{
+		DstAmount: amounts[len(amounts)-1].String(),
+		Amounts:   make([]string, len(amounts)),
+	}
+	for i, amount := range amounts {
+		response.Amounts[i] = amount.String()
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
+// validateListenAddr checks that addr is a host:port pair with a numeric
+// port. An empty host binds all interfaces.
+func validateListenAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+
+	return nil
+}
+
+// newEstimatorFromConfig dials the default node and sets up the estimator
+// and per-chain clients. The caller closes both clients.
+func newEstimatorFromConfig(cfg *Config) (*EthereumClient, *ChainClients, *SwapEstimator, error) {
+	ethClient, err := NewEthereumClient(cfg.NodeURL)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ethClient.SetMaxRetries(cfg.RPCMaxRetries)
+	ethClient.SetCircuitBreaker("default", cfg.BreakerThreshold, cfg.BreakerCooldown)
+	ethClient.SetReservesFromStorage(cfg.ReservesFromStorage)
+	ethClient.SetReservesCacheTTL(cfg.ReservesCacheTTL)
+	ethClient.SetBlockTag(cfg.BlockTag)
+
+	chains := NewChainClients(cfg.ChainNodeURLs, cfg.ChainContracts)
+	chains.SetMaxRetries(cfg.RPCMaxRetries)
+	chains.SetCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
+	chains.SetReservesFromStorage(cfg.ReservesFromStorage)
+	chains.SetReservesCacheTTL(cfg.ReservesCacheTTL)
+	chains.SetBlockTag(cfg.BlockTag)
+
+	estimator := NewSwapEstimatorWithFee(ethClient, cfg.Fee)
+	estimator.SetChainClients(chains)
+	estimator.SetWETH(cfg.WETH)
+	estimator.SetFactory(cfg.Factory)
+	estimator.SetFactoryFees(cfg.FactoryFees)
+	estimator.SetPoolAllowlist(cfg.PoolAllowlist)
+	estimator.SetPoolDenylist(cfg.PoolDenylist)
+	estimator.SetQuoterV3(cfg.QuoterV3)
+	estimator.SetRouter(cfg.Router)
+	estimator.SetMaxSrcAmount(cfg.MaxSrcAmount)
+	estimator.SetRPCTimeout(cfg.RPCTimeout)
+	estimator.SetResponseCacheTTL(cfg.ResponseCacheTTL)
+	estimator.SetSwapGasLimit(cfg.SwapGasLimit)
+	estimator.SetPriorityFee(cfg.PriorityFee)
+	estimator.SetAllowNodeOverride(cfg.AllowNodeOverride)
+	estimator.SetUSDPricePool(cfg.USDPricePool)
+	estimator.SetSlowRequestThreshold(cfg.SlowRequestThreshold)
+
+	return ethClient, chains, estimator, nil
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "estimate" {
+		os.Exit(runEstimateCommand(os.Args[2:]))
+	}
+
+	dotenvErr := godotenv.Load()
 
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
+	cfg, err := LoadConfig()
+	if err != nil {
+		fatal("Failed to load configuration", "error", err)
 	}
+	slog.SetDefault(newLogger(os.Stdout, cfg.LogLevel))
 
-	nodeURL := os.Getenv("ETH_NODE_URL")
-	if nodeURL == "" {
-		log.Fatal("ETH_NODE_URL environment variable is required")
+	if dotenvErr != nil {
+		slog.Info("No .env file found, using environment variables")
 	}
 
-	ethClient, err := NewEthereumClient(nodeURL)
+	version := buildInfo()
+	slog.Info("Build info", "git_commit", version.GitCommit, "build_time", version.BuildTime, "go_version", version.GoVersion)
+
+	ethClient, chains, estimator, err := newEstimatorFromConfig(cfg)
 	if err != nil {
-		log.Fatal("Failed to create Ethereum client:", err)
+		fatal("Failed to create Ethereum client", "error", err)
+	}
+	if len(cfg.PreloadPools) > 0 {
+		// Reserves are only worth reading when the cache will keep them
+		loaded := estimator.PreloadPools(context.Background(), cfg.PreloadPools, cfg.ReservesCacheTTL > 0)
+		slog.Info("Preloaded pools", "loaded", loaded, "failed", len(cfg.PreloadPools)-loaded)
+	}
+	if cfg.AllowNodeOverride {
+		slog.Warn("ALLOW_NODE_OVERRIDE is enabled: requests may choose their own node with node_url")
 	}
 
-	estimator := NewSwapEstimator(ethClient)
+	openAPI, err := openAPIHandler()
+	if err != nil {
+		fatal("Failed to build OpenAPI spec", "error", err)
+	}
 
 	r := mux.NewRouter()
-	r.HandleFunc("/health", healthHandler).Methods("GET")
-    r.HandleFunc("/estimate", estimator.estimateHandler).Methods("GET")
+	r.HandleFunc("/openapi.json", openAPI).Methods("GET")
+	r.HandleFunc("/live", liveHandler).Methods("GET")
+	r.HandleFunc("/version", versionHandler).Methods("GET")
+	r.HandleFunc("/health", estimator.healthHandler).Methods("GET")
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	r.HandleFunc("/estimate", instrumentHandler("estimate", estimator.estimateHandler)).Methods("GET")
+	r.HandleFunc("/estimate", instrumentHandler("estimate", estimator.estimatePostHandler)).Methods("POST")
+	r.HandleFunc("/estimate_batch", instrumentHandler("estimate_batch", estimator.estimateBatchHandler)).Methods("POST")
+	r.HandleFunc("/estimate_by_tokens", instrumentHandler("estimate_by_tokens", estimator.estimateByTokensHandler)).Methods("GET")
+	r.HandleFunc("/estimate_exact_out", instrumentHandler("estimate_exact_out", estimator.estimateExactOutHandler)).Methods("GET")
+	r.HandleFunc("/quote", instrumentHandler("quote", estimator.quoteHandler)).Methods("GET")
+	r.HandleFunc("/estimate_route", instrumentHandler("estimate_route", estimator.estimateRouteHandler)).Methods("GET")
+	r.HandleFunc("/price", instrumentHandler("price", estimator.priceHandler)).Methods("GET")
+	r.HandleFunc("/trade", instrumentHandler("trade", estimator.tradeHandler)).Methods("GET")
+	r.HandleFunc("/estimate_both", instrumentHandler("estimate_both", estimator.bothSidesHandler)).Methods("GET")
+	r.HandleFunc("/estimate_best", instrumentHandler("estimate_best", estimator.estimateBestHandler)).Methods("GET")
+	r.HandleFunc("/max_amount_in", instrumentHandler("max_amount_in", estimator.maxAmountInHandler)).Methods("GET")
+	r.HandleFunc("/depth", instrumentHandler("depth", estimator.depthHandler)).Methods("GET")
+	r.HandleFunc("/estimate_split", instrumentHandler("estimate_split", estimator.estimateSplitHandler)).Methods("GET")
+	r.HandleFunc("/estimate_v3", instrumentHandler("estimate_v3", estimator.estimateV3Handler)).Methods("GET")
+	r.HandleFunc("/reserves", instrumentHandler("reserves", estimator.reservesHandler)).Methods("GET")
+	r.HandleFunc("/pairs", instrumentHandler("pairs", estimator.pairsHandler)).Methods("GET")
+	r.HandleFunc("/pools/{address}/tokens", instrumentHandler("pool_tokens", estimator.poolTokensHandler)).Methods("GET")
+	r.HandleFunc("/ws/quote", estimator.quoteStreamHandler(cfg.CORSOrigins)).Methods("GET")
+	r.MethodNotAllowedHandler = methodNotAllowedHandler(r)
+
+	// gRPC calls share the REST API's limits through interceptors
+	var grpcInterceptors []grpc.UnaryServerInterceptor
+
+	if cfg.RateLimitRPS > 0 {
+		limiter := newIPRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst)
+		defer limiter.Close()
+		r.Use(limiter.Middleware)
+		grpcInterceptors = append(grpcInterceptors, rateLimitInterceptor(limiter))
+	}
+
+	// Registered after the rate limiter so throttled clients don't take slots
+	if cfg.MaxInFlight > 0 {
+		inFlight := newInFlightLimiter(cfg.MaxInFlight)
+		r.Use(inFlight.Middleware)
+		grpcInterceptors = append(grpcInterceptors, loadShedInterceptor(inFlight))
+	}
+
+	handler := gzipMiddleware(corsMiddleware(cfg.CORSOrigins)(r))
+	if cfg.AccessLog {
+		handler = accessLogMiddleware(handler, cfg.SlowRequestThreshold)
+	}
+
+	// WebSocket connections are hijacked, which clears these deadlines, so
+	// WriteTimeout doesn't cut off /ws streams
+	srv := &http.Server{
+		Addr:         cfg.ListenAddr,
+		Handler:      requestIDMiddleware(handler),
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+	}
+
+	grpcListener, err := net.Listen("tcp", cfg.GRPCListenAddr)
+	if err != nil {
+		fatal("Failed to listen for gRPC", "addr", cfg.GRPCListenAddr, "error", err)
+	}
+	grpcSrv := newGRPCServer(estimator, grpcInterceptors...)
+
+	serverErr := make(chan error, 2)
+	go func() {
+		slog.Info("Starting server", "addr", cfg.ListenAddr)
+		serverErr <- srv.ListenAndServe()
+	}()
+	go func() {
+		slog.Info("Starting gRPC server", "addr", cfg.GRPCListenAddr)
+		serverErr <- grpcSrv.Serve(grpcListener)
+	}()
+
+	if cfg.PoolDenylist != nil {
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		go func() {
+			for range reload {
+				denied, err := estimator.ReloadPoolDenylist()
+				if err != nil {
+					slog.Warn("Failed to reload pool denylist, keeping the previous one", "error", err)
+					continue
+				}
+				slog.Info("Reloaded pool denylist", "pools", denied)
+			}
+		}()
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErr:
+		fatal("Server failed", "error", err)
+	case sig := <-stop:
+		slog.Info("Shutting down", "signal", sig.String(), "grace_period", cfg.ShutdownTimeout.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// GracefulStop waits for in-flight RPCs with no deadline of its own
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcSrv.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("Graceful shutdown did not complete, forcing exit", "error", err)
+		srv.Close()
+	} else {
+		slog.Info("All in-flight requests drained")
+	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "1337"
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		slog.Warn("gRPC graceful shutdown did not complete, forcing exit")
+		grpcSrv.Stop()
 	}
 
-	log.Printf("Starting server on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	ethClient.Close()
+	chains.Close()
+	slog.Info("Server stopped")
 }
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	testPool   = common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc")
+	testToken0 = common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	testToken1 = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	testOther  = common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7")
+)
+
+// fakePair is the canned state of one pair in a fakeChain.
+type fakePair struct {
+	token0, token1 common.Address
+	reserves       *PoolReserves
+}
+
+// fakeChain is a ChainReader serving canned pair state. Methods it doesn't
+// implement panic through the nil embedded interface, so a test notices
+// calls it didn't expect.
+type fakeChain struct {
+	ChainReader
+	pairs map[common.Address]fakePair
+	// decimals is read by GetPairDecimals; tokens missing from it fail
+	decimals map[common.Address]uint8
+	// baseFee is the latest block's base fee; nil means the chain has none
+	baseFee *big.Int
+	// err fails every read when set
+	err error
+	// reserveReads counts reserve reads, batched ones counting once
+	reserveReads atomic.Int64
+}
+
+func newFakeChain(pairs map[common.Address]fakePair) *fakeChain {
+	return &fakeChain{pairs: pairs}
+}
+
+func (fc *fakeChain) pair(pairAddr common.Address) (fakePair, error) {
+	if fc.err != nil {
+		return fakePair{}, fc.err
+	}
+	p, ok := fc.pairs[pairAddr]
+	if !ok {
+		return fakePair{}, errEmptyResult
+	}
+	return p, nil
+}
+
+func (fc *fakeChain) GetToken0(ctx context.Context, pairAddr common.Address, blockNumber *big.Int) (common.Address, error) {
+	p, err := fc.pair(pairAddr)
+	return p.token0, err
+}
+
+func (fc *fakeChain) GetToken1(ctx context.Context, pairAddr common.Address, blockNumber *big.Int) (common.Address, error) {
+	p, err := fc.pair(pairAddr)
+	return p.token1, err
+}
+
+func (fc *fakeChain) GetReserves(ctx context.Context, pairAddr common.Address, blockNumber *big.Int) (*PoolReserves, error) {
+	fc.reserveReads.Add(1)
+	p, err := fc.pair(pairAddr)
+	if err != nil {
+		return nil, err
+	}
+	return copyReserves(p.reserves), nil
+}
+
+func (fc *fakeChain) GetPairTokensBatch(ctx context.Context, pairs []common.Address) ([][2]common.Address, error) {
+	tokens := make([][2]common.Address, len(pairs))
+	for i, pairAddr := range pairs {
+		p, err := fc.pair(pairAddr)
+		if err != nil {
+			return nil, err
+		}
+		tokens[i] = [2]common.Address{p.token0, p.token1}
+	}
+	return tokens, nil
+}
+
+func (fc *fakeChain) GetReservesBatch(ctx context.Context, pairs []common.Address) ([]*PoolReserves, []error, error) {
+	fc.reserveReads.Add(1)
+	if fc.err != nil {
+		return nil, nil, fc.err
+	}
+	reserves := make([]*PoolReserves, len(pairs))
+	errs := make([]error, len(pairs))
+	for i, pairAddr := range pairs {
+		p, err := fc.pair(pairAddr)
+		if err != nil {
+			errs[i] = pairCallError(pairAddr, "reserves", err)
+			continue
+		}
+		reserves[i] = copyReserves(p.reserves)
+	}
+	return reserves, errs, nil
+}
+
+func (fc *fakeChain) GetPairDecimals(ctx context.Context, tokenA, tokenB common.Address) (uint8, uint8, error) {
+	decimalsA, okA := fc.decimals[tokenA]
+	decimalsB, okB := fc.decimals[tokenB]
+	if !okA || !okB {
+		return 0, 0, errEmptyResult
+	}
+	return decimalsA, decimalsB, nil
+}
+
+func (fc *fakeChain) GetBaseFee(ctx context.Context) (*big.Int, error) {
+	if fc.baseFee == nil {
+		return nil, errNoBaseFee
+	}
+	return fc.baseFee, nil
+}
+
+// testReserves returns reserves of 100 token0 and 200 token1, both with 18
+// decimals.
+func testReserves(t testing.TB) *PoolReserves {
+	return &PoolReserves{
+		Reserve0:           bigInt(t, "100000000000000000000"),
+		Reserve1:           bigInt(t, "200000000000000000000"),
+		BlockTimestampLast: 1700000000,
+	}
+}
+
+// bigInt parses a base-10 literal, for amounts too large for an int64.
+func bigInt(t testing.TB, s string) *big.Int {
+	t.Helper()
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("invalid integer literal %q", s)
+	}
+	return n
+}
+
+func TestCalculateSwapAmount(t *testing.T) {
+	tests := []struct {
+		name                            string
+		amountIn, reserveIn, reserveOut string
+		fee                             SwapFee
+		want                            string
+	}{
+		// Reference values from UniswapV2Library.getAmountOut, computed
+		// independently with arbitrary-precision integers
+		{"one wei truncates to zero", "1", "1000000000000000000", "1000000000000000000", DefaultSwapFee, "0"},
+		{"small amount", "1000", "1000000000000000000", "2000000000000000000", DefaultSwapFee, "1993"},
+		{"equal reserves", "10000000000000000", "1000000000000000000", "1000000000000000000", DefaultSwapFee, "9871580343970612"},
+		{"amount far above reserve", "1000000000000000000000000", "1000000000000000000000", "1000000000000000000000", DefaultSwapFee, "998997995991983967935"},
+		{"imbalanced WETH to USDC", "1000000000000000000", "10000000000000000000000", "25000000000000", DefaultSwapFee, "2492251522"},
+		{"imbalanced USDC to WETH", "25000000000", "25000000000000", "10000000000000000000000", DefaultSwapFee, "9960069810399032164"},
+		{"25 bps fee", "1000000000000000000", "1000000000000000000", "1000000000000000000", SwapFeeFromBps(25), "499374217772215269"},
+		{"zero input", "0", "1000000000000000000", "1000000000000000000", DefaultSwapFee, "0"},
+		{"zero reserve in", "1000", "0", "1000000000000000000", DefaultSwapFee, "0"},
+		{"zero reserve out", "1000", "1000000000000000000", "0", DefaultSwapFee, "0"},
+		{"zero everything", "0", "0", "0", DefaultSwapFee, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculateSwapAmount(bigInt(t, tt.amountIn), bigInt(t, tt.reserveIn), bigInt(t, tt.reserveOut), tt.fee)
+			if got.String() != tt.want {
+				t.Errorf("calculateSwapAmount = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCalculateSwapAmountTruncates checks that the output is rounded down,
+// as the router does, so a quote never promises more than the pool pays.
+func TestCalculateSwapAmountTruncates(t *testing.T) {
+	reserveIn := bigInt(t, "123456789012345678901")
+	reserveOut := bigInt(t, "987654321098765432109")
+
+	for _, in := range []string{"1", "7", "999999", "31415926535897932", "271828182845904523536"} {
+		amountIn := bigInt(t, in)
+		got := calculateSwapAmount(amountIn, reserveIn, reserveOut, DefaultSwapFee)
+
+		// exact = amountIn*997*reserveOut / (reserveIn*1000 + amountIn*997)
+		withFee := new(big.Int).Mul(amountIn, big.NewInt(997))
+		exact := new(big.Rat).SetFrac(
+			new(big.Int).Mul(withFee, reserveOut),
+			new(big.Int).Add(new(big.Int).Mul(reserveIn, big.NewInt(1000)), withFee),
+		)
+
+		floor := new(big.Rat).SetInt(got)
+		next := new(big.Rat).SetInt(new(big.Int).Add(got, big.NewInt(1)))
+		if floor.Cmp(exact) > 0 || next.Cmp(exact) <= 0 {
+			t.Errorf("amountIn %s: got %s, want floor of %s", in, got, exact.FloatString(6))
+		}
+	}
+}
+
+func TestCalculateSwapAmountInRoundsUp(t *testing.T) {
+	tests := []struct {
+		name                             string
+		amountOut, reserveIn, reserveOut string
+		fee                              SwapFee
+	}{
+		{"small output", "1", "1000000", "1000000", DefaultSwapFee},
+		{"exact division", "1000", "997000", "1001000", DefaultSwapFee},
+		{"large output", "900000", "1000000", "1000000", DefaultSwapFee},
+		{"imbalanced reserves", "1000000", "5000000000000000000000", "12000000000000", DefaultSwapFee},
+		{"custom fee", "123456789", "987654321987654321", "555555555555", SwapFeeFromBps(25)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amountOut := bigInt(t, tt.amountOut)
+			reserveIn := bigInt(t, tt.reserveIn)
+			reserveOut := bigInt(t, tt.reserveOut)
+
+			amountIn, err := calculateSwapAmountIn(amountOut, reserveIn, reserveOut, tt.fee)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := calculateSwapAmount(amountIn, reserveIn, reserveOut, tt.fee); got.Cmp(amountOut) < 0 {
+				t.Fatalf("amountIn %s buys %s, want at least %s", amountIn, got, amountOut)
+			}
+
+			// Like getAmountIn, the +1 is added even when the division is
+			// exact, so at most one unit is overpaid
+			less := new(big.Int).Sub(amountIn, big.NewInt(2))
+			if got := calculateSwapAmount(less, reserveIn, reserveOut, tt.fee); got.Cmp(amountOut) >= 0 {
+				t.Errorf("amountIn %s also buys %s, so %s overpays by more than one unit", less, got, amountIn)
+			}
+		})
+	}
+}
+
+func TestCalculatePriceImpact(t *testing.T) {
+	reserveIn := bigInt(t, "1000000000000000000000")  // 1000 tokens
+	reserveOut := bigInt(t, "2000000000000000000000") // 2000 tokens
+	noFee := SwapFee{Numerator: 1, Denominator: 1}
+
+	tests := []struct {
+		name     string
+		amountIn *big.Int
+		fee      SwapFee
+		min, max float64
+	}{
+		// Only the LP fee moves the price of a dust trade
+		{"tiny amount", big.NewInt(1000000000000), DefaultSwapFee, 0.3, 0.3001},
+		{"tiny amount without fee", big.NewInt(1000000000000), noFee, 0, 0.000001},
+		{"one percent of reserve", bigInt(t, "10000000000000000000"), noFee, 0.99, 0.991},
+		{"whole reserve", reserveIn, noFee, 50, 50},
+		{"whole reserve with fee", reserveIn, DefaultSwapFee, 50.07, 50.08},
+		{"ten times reserve", new(big.Int).Mul(reserveIn, big.NewInt(10)), noFee, 90.9, 90.91},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amountOut := calculateSwapAmount(tt.amountIn, reserveIn, reserveOut, tt.fee)
+			impact, _ := calculatePriceImpact(tt.amountIn, amountOut, reserveIn, reserveOut).Float64()
+			if impact < tt.min || impact > tt.max {
+				t.Errorf("impact = %v%%, want between %v%% and %v%%", impact, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestCalculatePriceImpactZeroInput(t *testing.T) {
+	impact := calculatePriceImpact(new(big.Int), new(big.Int), big.NewInt(1000), big.NewInt(1000))
+	if impact.Sign() != 0 {
+		t.Errorf("impact = %s, want 0", impact.RatString())
+	}
+}
+
+func TestCalculateSwapAmountInFullFee(t *testing.T) {
+	_, err := calculateSwapAmountIn(big.NewInt(1000), big.NewInt(1000000), big.NewInt(1000000), SwapFeeFromBps(10000))
+	if err == nil {
+		t.Fatal("expected an error for a 100% fee")
+	}
+}
+
+func TestEstimateSwap(t *testing.T) {
+	oneToken := bigInt(t, "1000000000000000000")
+	emptyPool := common.HexToAddress("0x0d4a11d5EEaaC28EC3F61d100daF4d40471f1852")
+	missingPool := common.HexToAddress("0x397FF1542f962076d0BFE58eA045FfA2d347ACa0")
+
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {testToken0, testToken1, testReserves(t)},
+		emptyPool: {testToken0, testToken1, &PoolReserves{
+			Reserve0: new(big.Int),
+			Reserve1: bigInt(t, "200000000000000000000"),
+		}},
+	})
+
+	tests := []struct {
+		name           string
+		chain          ChainReader
+		pool, src, dst common.Address
+		wantOut        string
+		wantZeroForOne bool
+		wantErr        error
+	}{
+		// Reference outputs from getAmountOut with a 0.3% fee
+		{name: "token0 to token1", chain: chain, pool: testPool, src: testToken0, dst: testToken1, wantOut: "1974316068794122597", wantZeroForOne: true},
+		{name: "token1 to token0", chain: chain, pool: testPool, src: testToken1, dst: testToken0, wantOut: "496027303890107812"},
+		{name: "token not in pool", chain: chain, pool: testPool, src: testToken0, dst: testOther, wantErr: ErrTokenMismatch},
+		{name: "reversed pair of other tokens", chain: chain, pool: testPool, src: testOther, dst: testToken0, wantErr: ErrTokenMismatch},
+		{name: "zero reserve", chain: chain, pool: emptyPool, src: testToken0, dst: testToken1, wantErr: ErrNoLiquidity},
+		{name: "no contract", chain: chain, pool: missingPool, src: testToken0, dst: testToken1, wantErr: ErrPoolNotFound},
+		{name: "rpc error", chain: &fakeChain{err: errors.New("dial tcp: connection refused")}, pool: testPool, src: testToken0, dst: testToken1, wantErr: ErrRPCFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			se := NewSwapEstimator(tt.chain)
+			estimate, err := se.EstimateSwap(context.Background(), tt.pool, tt.src, tt.dst, oneToken)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := estimate.AmountOut.String(); got != tt.wantOut {
+				t.Errorf("AmountOut = %s, want %s", got, tt.wantOut)
+			}
+			if estimate.ZeroForOne != tt.wantZeroForOne {
+				t.Errorf("ZeroForOne = %v, want %v", estimate.ZeroForOne, tt.wantZeroForOne)
+			}
+			if estimate.Token0 != testToken0 || estimate.Token1 != testToken1 {
+				t.Errorf("tokens = %s/%s, want %s/%s", estimate.Token0.Hex(), estimate.Token1.Hex(), testToken0.Hex(), testToken1.Hex())
+			}
+		})
+	}
+}
+
+func BenchmarkCalculateSwapAmount(b *testing.B) {
+	amountIn := bigInt(b, "1000000000000000000")
+	reserveIn := bigInt(b, "12345678901234567890123")
+	reserveOut := bigInt(b, "98765432109876543210987")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		calculateSwapAmount(amountIn, reserveIn, reserveOut, DefaultSwapFee)
+	}
+}
+
+// calculateSwapAmountUnpooled is calculateSwapAmount without the pooled
+// scratch space: the straightforward version it must stay identical to.
+func calculateSwapAmountUnpooled(amountIn, reserveIn, reserveOut *big.Int, fee SwapFee) *big.Int {
+	if amountIn.Sign() <= 0 || reserveIn.Sign() <= 0 || reserveOut.Sign() <= 0 {
+		return new(big.Int)
+	}
+
+	amountInWithFee := new(big.Int).Mul(amountIn, big.NewInt(fee.Numerator))
+	numerator := new(big.Int).Mul(amountInWithFee, reserveOut)
+	denominator := new(big.Int).Mul(reserveIn, big.NewInt(fee.Denominator))
+	denominator.Add(denominator, amountInWithFee)
+	return numerator.Div(numerator, denominator)
+}
+
+func TestCalculateSwapAmountMatchesUnpooled(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomAmount := func() *big.Int {
+		// Up to 2^128, past the uint112 reserves a pair can hold
+		return new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(rng.Intn(129))))
+	}
+	fees := []SwapFee{DefaultSwapFee, SwapFeeFromBps(0), SwapFeeFromBps(25), SwapFeeFromBps(9999)}
+
+	for i := 0; i < 10000; i++ {
+		amountIn, reserveIn, reserveOut := randomAmount(), randomAmount(), randomAmount()
+		fee := fees[i%len(fees)]
+
+		got := calculateSwapAmount(amountIn, reserveIn, reserveOut, fee)
+		want := calculateSwapAmountUnpooled(amountIn, reserveIn, reserveOut, fee)
+		if got.Cmp(want) != 0 {
+			t.Fatalf("calculateSwapAmount(%s, %s, %s, %+v) = %s, unpooled %s", amountIn, reserveIn, reserveOut, fee, got, want)
+		}
+	}
+}
+
+// TestCalculateSwapAmountReturnsFreshResult checks that results don't share
+// memory with pooled scratch space a later call could overwrite.
+func TestCalculateSwapAmountReturnsFreshResult(t *testing.T) {
+	reserveIn := bigInt(t, "100000000000000000000")
+	reserveOut := bigInt(t, "200000000000000000000")
+
+	first := calculateSwapAmount(bigInt(t, "1000000000000000000"), reserveIn, reserveOut, DefaultSwapFee)
+	want := first.String()
+	for i := 0; i < 100; i++ {
+		calculateSwapAmount(bigInt(t, "50000000000000000000"), reserveIn, reserveOut, DefaultSwapFee)
+	}
+	if first.String() != want {
+		t.Fatalf("first result changed from %s to %s", want, first)
+	}
+}
+
+func BenchmarkCalculateSwapAmountUnpooled(b *testing.B) {
+	amountIn := bigInt(b, "1000000000000000000")
+	reserveIn := bigInt(b, "12345678901234567890123")
+	reserveOut := bigInt(b, "98765432109876543210987")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		calculateSwapAmountUnpooled(amountIn, reserveIn, reserveOut, DefaultSwapFee)
+	}
+}
+
+func BenchmarkEstimateSwap(b *testing.B) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(b)},
+	})
+	se := NewSwapEstimator(chain)
+	ctx := context.Background()
+	amountIn := bigInt(b, "1000000000000000000")
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := se.EstimateSwap(ctx, testPool, testToken0, testToken1, amountIn); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func FuzzCalculateSwapAmount(f *testing.F) {
+	f.Add([]byte{0x0d, 0xe0, 0xb6, 0xb3, 0xa7, 0x64, 0x00, 0x00}, []byte{0x05, 0x6b, 0xc7, 0x5e, 0x2d, 0x63, 0x10, 0x00, 0x00}, []byte{0x0a, 0xd7, 0x8e, 0xbc, 0x5a, 0xc6, 0x20, 0x00, 0x00}, uint64(1), uint16(30), false)
+	f.Add([]byte{}, []byte{}, []byte{}, uint64(0), uint16(0), false)
+	f.Add([]byte{1}, []byte{1}, []byte{1}, uint64(1<<63), uint16(9999), true)
+	f.Add(bytes32(0xff), bytes32(0xff), bytes32(0xff), uint64(1), uint16(30), false)
+
+	f.Fuzz(func(t *testing.T, amountInBytes, reserveInBytes, reserveOutBytes []byte, delta uint64, feeBps uint16, negative bool) {
+		amountIn := new(big.Int).SetBytes(amountInBytes)
+		reserveIn := new(big.Int).SetBytes(reserveInBytes)
+		reserveOut := new(big.Int).SetBytes(reserveOutBytes)
+		if negative {
+			amountIn.Neg(amountIn)
+		}
+		fee := SwapFeeFromBps(int64(feeBps % 10000))
+
+		out := calculateSwapAmount(amountIn, reserveIn, reserveOut, fee)
+		if out.Sign() < 0 {
+			t.Fatalf("negative output %s", out)
+		}
+		if reserveOut.Sign() > 0 && out.Cmp(reserveOut) >= 0 {
+			t.Fatalf("output %s drains reserve %s", out, reserveOut)
+		}
+
+		// Spending more never buys less
+		more := new(big.Int).Add(amountIn, new(big.Int).SetUint64(delta))
+		if outMore := calculateSwapAmount(more, reserveIn, reserveOut, fee); outMore.Cmp(out) < 0 {
+			t.Fatalf("amountIn %s buys %s but %s buys only %s", amountIn, out, more, outMore)
+		}
+	})
+}
+
+func bytes32(b byte) []byte {
+	s := make([]byte, 32)
+	for i := range s {
+		s[i] = b
+	}
+	return s
+}
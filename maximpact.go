@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type MaxAmountInResponse struct {
+	// SrcAmount is the largest input whose price impact doesn't exceed the
+	// target
+	SrcAmount   string `json:"src_amount"`
+	DstAmount   string `json:"dst_amount"`
+	PriceImpact string `json:"price_impact"`
+}
+
+// feeImpactPercent is the price impact of the fee alone, which every trade
+// pays however small: (1 − γ)·100.
+func feeImpactPercent(fee SwapFee) *big.Rat {
+	return big.NewRat((fee.Denominator-fee.Numerator)*100, fee.Denominator)
+}
+
+// amountInForPriceImpact inverts calculatePriceImpact for a constant-product
+// pool. The impact of an input x is 1 − γ·rIn/(rIn + γx), so for a target
+// impact I
+//
+//	x = rIn·(γ − (1 − I)) / (γ·(1 − I))
+//
+// The result is rounded down, so its impact only exceeds the target by
+// however much flooring the output to whole base units adds.
+// impactPercent must lie strictly between feeImpactPercent and 100.
+func amountInForPriceImpact(impactPercent *big.Rat, reserveIn *big.Int, fee SwapFee) *big.Int {
+	gamma := big.NewRat(fee.Numerator, fee.Denominator)
+	remaining := new(big.Rat).Sub(big.NewRat(1, 1), new(big.Rat).Quo(impactPercent, big.NewRat(100, 1)))
+
+	x := new(big.Rat).Sub(gamma, remaining)
+	x.Quo(x, new(big.Rat).Mul(gamma, remaining))
+	x.Mul(x, new(big.Rat).SetInt(reserveIn))
+
+	return new(big.Int).Quo(x.Num(), x.Denom())
+}
+
+// EstimateMaxAmountIn returns the largest swap from srcToken to dstToken
+// whose price impact stays within impactPercent.
+func (se *SwapEstimator) EstimateMaxAmountIn(ctx context.Context, poolAddr, srcToken, dstToken common.Address, impactPercent *big.Rat, blockNumber *big.Int) (*MaxAmountInResponse, error) {
+	reserves, err := se.getDirectionalReserves(ctx, poolAddr, srcToken, dstToken, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	amountIn := amountInForPriceImpact(impactPercent, reserves.ReserveIn, se.fee)
+	amountOut := calculateSwapAmount(amountIn, reserves.ReserveIn, reserves.ReserveOut, se.fee)
+	if err := checkAmountOut(amountOut, reserves.ReserveOut); err != nil {
+		return nil, fmt.Errorf("pool %s: %w", poolAddr.Hex(), err)
+	}
+
+	return &MaxAmountInResponse{
+		SrcAmount:   amountIn.String(),
+		DstAmount:   amountOut.String(),
+		PriceImpact: calculatePriceImpact(amountIn, amountOut, reserves.ReserveIn, reserves.ReserveOut).FloatString(4),
+	}, nil
+}
+
+func (se *SwapEstimator) maxAmountInHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	poolStr := query.Get("pool")
+	srcStr := query.Get("src")
+	dstStr := query.Get("dst")
+	impactStr := query.Get("price_impact")
+
+	if poolStr == "" || srcStr == "" || dstStr == "" || impactStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameters: pool, src, dst, price_impact")
+		return
+	}
+
+	poolAddr, err := parseAddress("pool", poolStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	blockNumber, err := parseBlockParams(query.Get("block"), query.Get("block_tag"))
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	se, err = se.forChain(query.Get("chain_id"))
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if !errors.Is(err, ErrChainNotConfigured) {
+			slog.ErrorContext(r.Context(), "max amount in estimate failed", "pool", poolStr, "error", err)
+			status, message = http.StatusInternalServerError, "Failed to connect to chain"
+		}
+		writeError(w, status, errorCode(status, err), message)
+		return
+	}
+
+	// The fee alone costs feeImpactPercent, so no trade can have less impact
+	minImpact := feeImpactPercent(se.fee)
+	impactPercent, ok := new(big.Rat).SetString(impactStr)
+	if !ok || impactPercent.Cmp(minImpact) <= 0 || impactPercent.Cmp(big.NewRat(100, 1)) >= 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("Invalid price_impact: must be a percentage above the %s%% fee and below 100", minImpact.FloatString(2)))
+		return
+	}
+
+	tokens, err := se.resolveSwapTokens(srcStr, dstStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	response, err := se.EstimateMaxAmountIn(ctx, poolAddr, tokens.Src, tokens.Dst, impactPercent, blockNumber)
+	if err != nil {
+		slog.WarnContext(ctx, "max amount in estimate failed", "pool", poolStr, "src", srcStr, "dst", dstStr, "price_impact", impactStr, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
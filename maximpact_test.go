@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAmountInForPriceImpact(t *testing.T) {
+	noFee := SwapFeeFromBps(0)
+
+	tests := []struct {
+		name   string
+		impact *big.Rat
+		fee    SwapFee
+		want   string
+	}{
+		{"0.5% with the fee", big.NewRat(1, 2), DefaultSwapFee, "201609854689697232"},
+		{"1% with the fee", big.NewRat(1, 1), DefaultSwapFee, "709198301976636981"},
+		{"5% with the fee", big.NewRat(5, 1), DefaultSwapFee, "4962255186612468985"},
+		{"50% with the fee", big.NewRat(50, 1), DefaultSwapFee, "99699097291875626880"},
+		{"1% without a fee", big.NewRat(1, 1), noFee, "1010101010101010101"},
+		// Swapping the whole reserve in halves the price
+		{"50% without a fee", big.NewRat(50, 1), noFee, "100000000000000000000"},
+	}
+
+	reserves := testReserves(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := amountInForPriceImpact(tt.impact, reserves.Reserve0, tt.fee)
+			if got.String() != tt.want {
+				t.Errorf("amountInForPriceImpact = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAmountInForPriceImpactInverts swaps the solved input and checks its
+// impact lands on the target, while a slightly larger input overshoots it.
+func TestAmountInForPriceImpactInverts(t *testing.T) {
+	reserves := testReserves(t)
+	reserveIn, reserveOut := reserves.Reserve0, reserves.Reserve1
+	// Flooring the output to whole base units adds a little impact
+	tolerance := big.NewRat(1, 1000000)
+
+	for _, target := range []*big.Rat{big.NewRat(1, 2), big.NewRat(1, 1), big.NewRat(3, 1), big.NewRat(25, 1), big.NewRat(90, 1)} {
+		amountIn := amountInForPriceImpact(target, reserveIn, DefaultSwapFee)
+		impact := calculatePriceImpact(amountIn, calculateSwapAmount(amountIn, reserveIn, reserveOut, DefaultSwapFee), reserveIn, reserveOut)
+
+		if diff := new(big.Rat).Sub(impact, target); diff.Abs(diff).Cmp(tolerance) > 0 {
+			t.Errorf("target %s%%: input %s has impact %s%%", target.FloatString(2), amountIn, impact.FloatString(8))
+		}
+
+		larger := new(big.Int).Add(amountIn, new(big.Int).Quo(amountIn, big.NewInt(1000)))
+		largerImpact := calculatePriceImpact(larger, calculateSwapAmount(larger, reserveIn, reserveOut, DefaultSwapFee), reserveIn, reserveOut)
+		if largerImpact.Cmp(target) <= 0 {
+			t.Errorf("target %s%%: larger input %s still has impact %s%%", target.FloatString(2), larger, largerImpact.FloatString(8))
+		}
+	}
+}
+
+func TestEstimateMaxAmountIn(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+	})
+	se := NewSwapEstimator(chain)
+
+	got, err := se.EstimateMaxAmountIn(context.Background(), testPool, testToken0, testToken1, big.NewRat(1, 1), nil)
+	if err != nil {
+		t.Fatalf("EstimateMaxAmountIn: %v", err)
+	}
+
+	if got.SrcAmount != "709198301976636981" {
+		t.Errorf("src_amount = %s, want 709198301976636981", got.SrcAmount)
+	}
+	wantOut := calculateSwapAmount(bigInt(t, got.SrcAmount), bigInt(t, "100000000000000000000"), bigInt(t, "200000000000000000000"), DefaultSwapFee)
+	if got.DstAmount != wantOut.String() {
+		t.Errorf("dst_amount = %s, want %s", got.DstAmount, wantOut)
+	}
+	if got.PriceImpact != "1.0000" {
+		t.Errorf("price_impact = %s, want 1.0000", got.PriceImpact)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// methodNotAllowedHandler answers requests whose path exists under another
+// method with 405, listing the methods the path does accept in Allow.
+func methodNotAllowedHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed := allowedMethods(router, r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, fmt.Sprintf("Method %s not allowed; use %s", r.Method, strings.Join(allowed, " or ")))
+	})
+}
+
+// allowedMethods collects the methods of every route registered for path.
+func allowedMethods(router *mux.Router, path string) []string {
+	var allowed []string
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		pathRegexp, err := route.GetPathRegexp()
+		if err != nil {
+			return nil
+		}
+		if matched, _ := regexp.MatchString(pathRegexp, path); !matched {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if !slices.Contains(allowed, method) {
+				allowed = append(allowed, method)
+			}
+		}
+		return nil
+	})
+	return allowed
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	estimateRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "estimator_requests_total",
+		Help: "Total number of estimate requests.",
+	}, []string{"endpoint", "pool_hash"})
+
+	estimateErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "estimator_errors_total",
+		Help: "Total number of failed estimate requests by error type.",
+	}, []string{"endpoint", "type"})
+
+	estimateDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "estimator_request_duration_seconds",
+		Help:    "Latency of estimate requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	rpcCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "estimator_rpc_call_duration_seconds",
+		Help:    "Latency of eth_call requests made to the Ethereum node.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "outcome"})
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// instrumentHandler records request counts, error types and latency for an
+// estimate endpoint.
+func instrumentHandler(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next(rec, r)
+
+		estimateRequestsTotal.WithLabelValues(endpoint, poolHashLabel(r.URL.Query().Get("pool"))).Inc()
+		estimateDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+
+		if rec.status >= http.StatusBadRequest {
+			errType := strings.ReplaceAll(strings.ToLower(http.StatusText(rec.status)), " ", "_")
+			estimateErrorsTotal.WithLabelValues(endpoint, errType).Inc()
+		}
+	}
+}
+
+func observeRPCCall(method string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	rpcCallDuration.WithLabelValues(method, outcome).Observe(time.Since(start).Seconds())
+}
+
+// poolHashLabel buckets pool addresses into at most 256 label values so an
+// attacker cycling through addresses can't blow up series cardinality.
+func poolHashLabel(pool string) string {
+	if pool == "" {
+		return "none"
+	}
+	hash := crypto.Keccak256(common.HexToAddress(pool).Bytes())
+	return common.Bytes2Hex(hash[:1])
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Multicall3 is deployed at the same address on mainnet and most EVM chains.
+var multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicall3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// GetReservesBatch reads the latest reserves of every pair in a single
+// Multicall3 call. errs[i] is set instead of reserves[i] when pair i failed,
+// classified like pairCallError; err is only returned when the call as a
+// whole failed. Cached reserves are served from the cache, and without
+// Multicall3, or with RESERVES_FROM_STORAGE, each pair is read on its own.
+func (ec *EthereumClient) GetReservesBatch(ctx context.Context, pairs []common.Address) ([]*PoolReserves, []error, error) {
+	reserves := make([]*PoolReserves, len(pairs))
+	errs := make([]error, len(pairs))
+	if ec.reservesFromStorage {
+		ec.getReservesSequential(ctx, pairs, reserves, errs)
+		return reserves, errs, nil
+	}
+	var (
+		missing  []int
+		versions []uint64
+	)
+	for i, pair := range pairs {
+		if rc := ec.reservesCache; rc != nil {
+			cached, version := rc.get(pair)
+			if cached != nil {
+				reserves[i] = copyReserves(cached)
+				continue
+			}
+			versions = append(versions, version)
+		}
+		missing = append(missing, i)
+	}
+	if len(missing) == 0 {
+		return reserves, errs, nil
+	}
+
+	data, err := ec.packPairCall("getReserves")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	calls := make([]multicall3Call, len(missing))
+	for j, i := range missing {
+		calls[j] = multicall3Call{
+			Target:       pairs[i],
+			AllowFailure: true,
+			CallData:     data,
+		}
+	}
+
+	results, err := ec.aggregate3(ctx, calls)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// No code at the multicall address on this chain
+	if results == nil {
+		ec.getReservesSequential(ctx, pairs, reserves, errs)
+		return reserves, errs, nil
+	}
+
+	if len(results) != len(calls) {
+		return nil, nil, fmt.Errorf("unexpected multicall result length: got %d, want %d", len(results), len(calls))
+	}
+
+	for j, i := range missing {
+		res := results[j]
+		switch {
+		case !res.Success:
+			errs[i] = fmt.Errorf("%w: %s failed to return reserves", ErrNotUniswapV2Pair, pairs[i].Hex())
+			continue
+		case len(res.ReturnData) == 0:
+			// A call to an address without code succeeds with no data
+			errs[i] = pairCallError(pairs[i], "reserves", errEmptyResult)
+			continue
+		}
+
+		r, err := ec.unpackReserves(res.ReturnData)
+		if err != nil {
+			errs[i] = pairCallError(pairs[i], "reserves", err)
+			continue
+		}
+
+		reserves[i] = r
+		if rc := ec.reservesCache; rc != nil {
+			if rc.put(pairs[i], r, versions[j]) {
+				go ec.watchSync(rc, pairs[i])
+			}
+			reserves[i] = copyReserves(r)
+		}
+	}
+
+	return reserves, errs, nil
+}
+
+// aggregate3 returns nil results without an error when Multicall3 is not
+// deployed on the connected chain.
+func (ec *EthereumClient) aggregate3(ctx context.Context, calls []multicall3Call) ([]multicall3Result, error) {
+	data, err := ec.multicallABI.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack aggregate3 call: %w", err)
+	}
+
+	result, err := ec.callContract(ctx, "aggregate3", multicall3Address, data, nil)
+	if errors.Is(err, errEmptyResult) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to call aggregate3: %w", err)
+	}
+
+	unpacked, err := ec.multicallABI.Unpack("aggregate3", result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack aggregate3 result: %w", err)
+	}
+
+	if len(unpacked) == 0 {
+		return nil, fmt.Errorf("empty aggregate3 result")
+	}
+
+	results := *abi.ConvertType(unpacked[0], new([]multicall3Result)).(*[]multicall3Result)
+	return results, nil
+}
+
+// getReservesSequential fills in the pairs that have neither reserves nor an
+// error yet, one call at a time.
+func (ec *EthereumClient) getReservesSequential(ctx context.Context, pairs []common.Address, reserves []*PoolReserves, errs []error) {
+	for i, pair := range pairs {
+		if reserves[i] != nil || errs[i] != nil {
+			continue
+		}
+		r, err := ec.GetReserves(ctx, pair, nil)
+		if err != nil {
+			errs[i] = pairCallError(pair, "reserves", err)
+			continue
+		}
+		reserves[i] = r
+	}
+}
+
+// GetPairTokensBatch returns each pair's token0 and token1, reading the ones
+// not cached yet in a single Multicall3 call and caching them like
+// getPairToken. Unlike GetReservesBatch, one pair that can't be read fails
+// the whole batch.
+func (ec *EthereumClient) GetPairTokensBatch(ctx context.Context, pairs []common.Address) ([][2]common.Address, error) {
+	methods := []string{"token0", "token1"}
+	tokens := make([][2]common.Address, len(pairs))
+
+	type pending struct {
+		pair, side int
+	}
+	var (
+		calls   []multicall3Call
+		missing []pending
+	)
+	ec.pairTokensMu.RLock()
+	for i, pair := range pairs {
+		for side, method := range methods {
+			if token, ok := ec.pairTokens[pairTokenKey{pair, method}]; ok {
+				tokens[i][side] = token
+				continue
+			}
+			missing = append(missing, pending{i, side})
+		}
+	}
+	ec.pairTokensMu.RUnlock()
+
+	if len(missing) == 0 {
+		return tokens, nil
+	}
+
+	for _, m := range missing {
+		data, err := ec.packPairCall(methods[m.side])
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, multicall3Call{Target: pairs[m.pair], AllowFailure: true, CallData: data})
+	}
+
+	results, err := ec.aggregate3(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+	if results != nil && len(results) != len(calls) {
+		return nil, fmt.Errorf("unexpected multicall result length: got %d, want %d", len(results), len(calls))
+	}
+
+	for j, m := range missing {
+		pair, method := pairs[m.pair], methods[m.side]
+		if results == nil {
+			token, err := ec.getPairToken(ctx, pair, method, nil)
+			if err != nil {
+				return nil, pairCallError(pair, method, err)
+			}
+			tokens[m.pair][m.side] = token
+			continue
+		}
+
+		if !results[j].Success {
+			return nil, fmt.Errorf("%w: %s failed to return %s", ErrNotUniswapV2Pair, pair.Hex(), method)
+		}
+		unpacked, err := ec.abi.Unpack(method, results[j].ReturnData)
+		if err != nil || len(unpacked) == 0 {
+			return nil, fmt.Errorf("%w: failed to unpack %s result for %s", errMalformedResult, method, pair.Hex())
+		}
+		token, ok := unpacked[0].(common.Address)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast %s to common.Address", method)
+		}
+
+		tokens[m.pair][m.side] = token
+		if token != (common.Address{}) {
+			ec.pairTokensMu.Lock()
+			ec.pairTokens[pairTokenKey{pair, method}] = token
+			ec.pairTokensMu.Unlock()
+		}
+	}
+
+	return tokens, nil
+}
+
+// GetPoolStates reads the latest state of several pools, batching the token
+// and reserve reads into Multicall3 calls. errs[i] is set instead of
+// states[i] when pool i can't be read.
+func (se *SwapEstimator) GetPoolStates(ctx context.Context, pools []common.Address) ([]*PoolState, []error) {
+	states := make([]*PoolState, len(pools))
+	errs := make([]error, len(pools))
+
+	var allowed []int
+	for i, pool := range pools {
+		if err := se.checkPoolAllowed(pool); err != nil {
+			errs[i] = err
+			continue
+		}
+		allowed = append(allowed, i)
+	}
+	if len(allowed) == 0 {
+		return states, errs
+	}
+
+	pairs := make([]common.Address, len(allowed))
+	for j, i := range allowed {
+		pairs[j] = pools[i]
+	}
+
+	tokens, err := se.ethClient.GetPairTokensBatch(ctx, pairs)
+	if err != nil {
+		// One bad pool fails the whole token batch, so read each pool on its
+		// own to pin the error on the right one
+		var wg sync.WaitGroup
+		for _, i := range allowed {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				states[i], errs[i] = se.GetPoolState(ctx, pools[i], nil)
+			}()
+		}
+		wg.Wait()
+		return states, errs
+	}
+
+	reserves, reserveErrs, err := se.ethClient.GetReservesBatch(ctx, pairs)
+	for j, i := range allowed {
+		switch {
+		case tokens[j][0] == (common.Address{}) || tokens[j][1] == (common.Address{}):
+			errs[i] = fmt.Errorf("%w: %s reports a zero token address", ErrNotUniswapV2Pair, pools[i].Hex())
+		case err != nil:
+			errs[i] = pairCallError(pools[i], "reserves", err)
+		case reserveErrs[j] != nil:
+			errs[i] = reserveErrs[j]
+		default:
+			states[i] = &PoolState{PoolReserves: reserves[j], Token0: tokens[j][0], Token1: tokens[j][1]}
+		}
+	}
+
+	return states, errs
+}
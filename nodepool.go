@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// nodeDownCooldown is how long an endpoint is tried last after a node
+// failure, unless a call succeeds on it first.
+const nodeDownCooldown = 15 * time.Second
+
+// nodeEndpoint is one of the node URLs an EthereumClient spreads calls over.
+// Endpoints are identified by their position in the URL list in logs, since
+// URLs often embed API keys.
+type nodeEndpoint struct {
+	index int
+	url   string
+
+	// mu guards client, which is replaced when the connection breaks
+	mu     sync.RWMutex
+	client *ethclient.Client
+	closed bool
+
+	// downUntil is a UnixNano time; zero means healthy
+	downUntil atomic.Int64
+}
+
+// splitNodeURLs parses a comma-separated list of node URLs.
+func splitNodeURLs(s string) []string {
+	var urls []string
+	for _, url := range strings.Split(s, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// dialEndpoints connects to every URL in nodeURLs, which may be a
+// comma-separated list.
+func dialEndpoints(nodeURLs string) ([]*nodeEndpoint, error) {
+	urls := splitNodeURLs(nodeURLs)
+	if len(urls) == 0 {
+		return nil, errors.New("failed to connect to Ethereum node: no node URL given")
+	}
+
+	endpoints := make([]*nodeEndpoint, 0, len(urls))
+	for i, url := range urls {
+		client, err := ethclient.Dial(url)
+		if err != nil {
+			for _, ep := range endpoints {
+				ep.client.Close()
+			}
+			if len(urls) > 1 {
+				return nil, fmt.Errorf("failed to connect to Ethereum node %d: %w", i, err)
+			}
+			return nil, fmt.Errorf("failed to connect to Ethereum node: %w", err)
+		}
+		endpoints = append(endpoints, &nodeEndpoint{index: i, url: url, client: client})
+	}
+	return endpoints, nil
+}
+
+func (ep *nodeEndpoint) healthy() bool {
+	return time.Now().UnixNano() >= ep.downUntil.Load()
+}
+
+func (ep *nodeEndpoint) close() {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	ep.closed = true
+	ep.client.Close()
+}
+
+// markDown skips ep for nodeDownCooldown. Health isn't tracked with a single
+// endpoint, since there is nothing to fail over to.
+func (ec *EthereumClient) markDown(ep *nodeEndpoint, err error) {
+	if len(ec.endpoints) == 1 {
+		return
+	}
+	if ep.downUntil.Swap(time.Now().Add(nodeDownCooldown).UnixNano()) == 0 {
+		slog.Warn("Ethereum node endpoint failed, skipping it", "endpoint", ep.index, "cooldown", nodeDownCooldown, "error", err)
+	}
+}
+
+func (ec *EthereumClient) markUp(ep *nodeEndpoint) {
+	if len(ec.endpoints) == 1 {
+		return
+	}
+	if ep.downUntil.Swap(0) != 0 {
+		slog.Info("Ethereum node endpoint recovered", "endpoint", ep.index)
+	}
+}
+
+// primaryEndpoint is the first healthy endpoint, used for calls that aren't
+// spread round-robin such as subscriptions.
+func (ec *EthereumClient) primaryEndpoint() *nodeEndpoint {
+	for _, ep := range ec.endpoints {
+		if ep.healthy() {
+			return ep
+		}
+	}
+	return ec.endpoints[0]
+}
+
+// endpointOrder returns every endpoint starting from the next one in
+// round-robin order, with endpoints that failed recently moved to the end.
+func (ec *EthereumClient) endpointOrder() []*nodeEndpoint {
+	n := len(ec.endpoints)
+	if n == 1 {
+		return ec.endpoints
+	}
+
+	first := int((ec.nextEndpoint.Add(1) - 1) % uint64(n))
+	order := make([]*nodeEndpoint, 0, n)
+	var down []*nodeEndpoint
+	for i := range n {
+		ep := ec.endpoints[(first+i)%n]
+		if ep.healthy() {
+			order = append(order, ep)
+		} else {
+			down = append(down, ep)
+		}
+	}
+	return append(order, down...)
+}
+
+// callEndpoints sends an eth_call to the endpoints in endpointOrder, failing
+// over to the next one when the node itself fails. Reverts and other
+// contract failures are returned at once, since every node would agree.
+func (ec *EthereumClient) callEndpoints(ctx context.Context, method string, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var err error
+	for _, ep := range ec.endpointOrder() {
+		start := time.Now()
+		client := ep.conn()
+		var result []byte
+		result, err = client.CallContract(ctx, msg, blockNumber)
+		observeRPCCall(method, start, err)
+		ep.checkConnection(client, err)
+
+		if !isNodeFailure(err) {
+			ec.markUp(ep)
+			return result, err
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		ec.markDown(ep, err)
+	}
+	return nil, err
+}
@@ -0,0 +1,37 @@
+package main
+
+import "encoding/json"
+
+const (
+	numberFormatString = "string"
+	numberFormatNumber = "number"
+)
+
+// MarshalJSON writes the amounts as JSON strings, or as bare JSON numbers
+// when the request asked for number_format=number. Numbers are exact on the
+// wire, but most JSON parsers read them as float64, which can't represent
+// integers above 2^53.
+func (r EstimateResponse) MarshalJSON() ([]byte, error) {
+	// plain has the same fields without this method, so it doesn't recurse
+	type plain EstimateResponse
+	if !r.numberAmounts {
+		return json.Marshal(plain(r))
+	}
+
+	// The outer fields shadow the embedded string ones
+	return json.Marshal(struct {
+		plain
+		DstAmount           json.Number `json:"dst_amount"`
+		FeeAmount           json.Number `json:"fee_amount"`
+		MinDstAmount        json.Number `json:"min_dst_amount,omitempty"`
+		IntegratorFeeAmount json.Number `json:"integrator_fee_amount,omitempty"`
+		NetDstAmount        json.Number `json:"net_dst_amount,omitempty"`
+	}{
+		plain:               plain(r),
+		DstAmount:           json.Number(r.DstAmount),
+		FeeAmount:           json.Number(r.FeeAmount),
+		MinDstAmount:        json.Number(r.MinDstAmount),
+		IntegratorFeeAmount: json.Number(r.IntegratorFeeAmount),
+		NetDstAmount:        json.Number(r.NetDstAmount),
+	})
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// openAPIVersion is the version of this API reported in the spec.
+const openAPIVersion = "1.0.0"
+
+const (
+	exampleUSDTWETHPool = "0x0d4a11d5EEaaC28EC3F61d100daF4d40471f1852"
+	exampleUSDT         = "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	exampleWETH         = "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"
+)
+
+// openAPIParam describes a query parameter.
+type openAPIParam struct {
+	name        string
+	description string
+	required    bool
+	example     string
+}
+
+var (
+	poolParam = openAPIParam{"pool", "Uniswap V2 pair address", true, exampleUSDTWETHPool}
+	srcParam  = openAPIParam{"src", "Input token address, or ETH", true, exampleUSDT}
+	dstParam  = openAPIParam{"dst", "Output token address, or ETH", true, exampleWETH}
+	// optionalDstParam is for endpoints that infer dst from the pool
+	optionalDstParam = openAPIParam{"dst", "Output token address, or ETH; defaults to the pool's other token", false, exampleWETH}
+	srcAmountParam   = openAPIParam{"src_amount", "Input amount in the src token's base units", true, "10000000"}
+	feeBpsParam      = openAPIParam{"fee_bps", "LP fee in basis points, overriding the default 30", false, "25"}
+	blockParam       = openAPIParam{"block", "Block number to quote against instead of the latest block", false, "18000000"}
+	blockTagParam    = openAPIParam{"block_tag", "latest, safe or finalized; overrides BLOCK_TAG and can't be combined with block", false, "finalized"}
+	chainIDParam     = openAPIParam{"chain_id", "Chain to quote on; defaults to the ETH_NODE_URL chain", false, "42161"}
+)
+
+// estimateParamsSpec lists the parameters shared by /estimate and the
+// EstimateRequest POST body.
+var estimateParamsSpec = []openAPIParam{
+	poolParam, srcParam, optionalDstParam, srcAmountParam, feeBpsParam, blockParam, blockTagParam,
+	{"format", "raw (default) or decimal", false, "decimal"},
+	{"precision", "Decimal places shown with format=decimal, truncated; default 6", false, "4"},
+	chainIDParam,
+	{"slippage_bps", "Slippage tolerance in basis points; adds min_dst_amount", false, "50"},
+	{"integrator_fee_bps", "Integrator fee taken from the output, in basis points; adds net_dst_amount and integrator_fee_amount", false, "10"},
+	{"include_state", "Adds k, block_timestamp_last and reserves_age_seconds", false, "true"},
+	{"include_metadata", "Adds src_symbol and dst_symbol", false, "true"},
+	{"include_gas", "Adds gas, the EIP-1559 cost of the swap transaction at the latest base fee", false, "true"},
+	{"include_rate", "Adds rate, dst_amount/src_amount in base units as an exact {numerator, denominator} fraction in lowest terms", false, "true"},
+	{"include_liquidity_usd", "Adds liquidity_usd, the pool's approximate value in USD priced through USD_PRICE_POOL; 400 unless it is configured", false, "true"},
+	{"transfer_fee_bps", "Fee withheld by a fee-on-transfer src token, in basis points", false, "200"},
+	{"check_transfer_fee", "Simulate transfers of both tokens and add warnings for any transfer fee found", false, "true"},
+	{"check_rebasing", "Compare reserves with 10 blocks earlier and add possible_rebasing; costs two extra getReserves calls", false, "true"},
+	{"node_url", "Node to use for this request only; ignored unless ALLOW_NODE_OVERRIDE is set", false, "http://127.0.0.1:8545"},
+	{"engine", "local (default) computes dst_amount off-chain; router takes it from the router's getAmountsOut", false, "router"},
+	{"curve", "constant_product (default) is Uniswap V2's x*y=k; stableswap is Curve's two-coin invariant, for pegged pairs with equal decimals", false, "stableswap"},
+	{"amplification", "StableSwap amplification coefficient A, 1-1000000; default 100. Only valid with curve=stableswap", false, "200"},
+	{"number_format", "string (default) or number; number writes the amounts as JSON numbers, which many parsers round above 2^53", false, "number"},
+	{"owner", "Wallet that will swap; adds needs_approval and its src allowance for ROUTER_ADDRESS", false, "0x28C6c06298d514Db089934071355E5743bf21d60"},
+	{"revalidate", "Bypasses the response and reserves caches and adds block_number, the block the reserves were read at", false, "true"},
+	{"envelope", "Wraps the response as {data, meta}, where meta holds chain_id, the block_number quoted and a Unix timestamp", false, "true"},
+	{"debug", "calldata returns the hex-encoded getReserves/token0/token1 calls and the pool address they target, without calling the node", false, "calldata"},
+}
+
+// buildOpenAPISpec generates the OpenAPI 3.0 document for the API. Schemas
+// are derived from the response types so they can't drift from the handlers.
+func buildOpenAPISpec() map[string]any {
+	schemas := map[string]any{}
+	ref := func(v any) map[string]any {
+		t := reflect.TypeOf(v)
+		schemas[t.Name()] = schemaFor(t)
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+
+	errorResponses := func(statuses ...int) map[string]any {
+		responses := map[string]any{}
+		for _, status := range statuses {
+			responses[fmt.Sprint(status)] = map[string]any{
+				"description": http.StatusText(status),
+				"content":     jsonContent(ref(ErrorResponse{}), nil),
+			}
+		}
+		return responses
+	}
+
+	estimateErrors := []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+	operation := func(summary string, params []openAPIParam, okSchema map[string]any, example any, errorStatuses []int) map[string]any {
+		responses := errorResponses(errorStatuses...)
+		responses["200"] = map[string]any{
+			"description": "OK",
+			"content":     jsonContent(okSchema, example),
+		}
+
+		op := map[string]any{
+			"summary":   summary,
+			"responses": responses,
+		}
+		if len(params) > 0 {
+			op["parameters"] = queryParams(params)
+		}
+		return op
+	}
+
+	estimateExample := EstimateResponse{
+		DstAmount:   "6241000000000000",
+		PriceImpact: "0.3009",
+		FeeAmount:   "30000",
+		Token0:      exampleWETH,
+		Token1:      exampleUSDT,
+		ZeroForOne:  false,
+	}
+
+	estimatePost := operation("Estimate a swap from a JSON body", nil, ref(EstimateResponse{}), estimateExample, estimateErrors)
+	estimatePost["requestBody"] = map[string]any{
+		"required": true,
+		"content": jsonContent(ref(EstimateRequest{}), EstimateRequest{
+			Pool:      exampleUSDTWETHPool,
+			Src:       exampleUSDT,
+			Dst:       exampleWETH,
+			SrcAmount: "10000000",
+		}),
+	}
+
+	batchPost := operation("Estimate up to 100 swaps concurrently", []openAPIParam{
+		{"format", "json (default) or csv; csv, also chosen by Accept: text/csv, returns pool,src,dst,amount_in,amount_out,error rows", false, "csv"},
+	}, map[string]any{
+		"type":  "array",
+		"items": ref(BatchEstimateResult{}),
+	}, []BatchEstimateResult{{DstAmount: "6241000000000000"}, {Error: "Invalid src_amount: must be greater than zero"}}, []int{http.StatusBadRequest, http.StatusTooManyRequests})
+	batchPost["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)["text/csv"] = map[string]any{
+		"schema": map[string]any{"type": "string"},
+	}
+	batchPost["requestBody"] = map[string]any{
+		"required": true,
+		"content": jsonContent(map[string]any{
+			"type":     "array",
+			"items":    ref(EstimateRequest{}),
+			"maxItems": maxBatchSize,
+		}, nil),
+	}
+
+	healthGet := operation("Check that the Ethereum node is reachable", nil, ref(HealthResponse{}),
+		HealthResponse{Status: "ok", BlockNumber: 20000000}, nil)
+	healthGet["responses"].(map[string]any)["503"] = map[string]any{
+		"description": "Ethereum node unreachable",
+		"content":     jsonContent(ref(HealthResponse{}), nil),
+	}
+
+	poolTokensGet := operation("Read a pool's tokens, optionally with their symbols and decimals",
+		[]openAPIParam{
+			{"include_metadata", "Adds each token's symbol and decimals", false, "true"},
+			chainIDParam,
+		},
+		ref(PoolTokensResponse{}), nil, estimateErrors)
+	// queryParams only describes query parameters, so the path's address is
+	// added here
+	poolTokensGet["parameters"] = append([]map[string]any{{
+		"name":        "address",
+		"in":          "path",
+		"description": "Uniswap V2 pair address",
+		"required":    true,
+		"schema":      map[string]any{"type": "string"},
+		"example":     exampleUSDTWETHPool,
+	}}, poolTokensGet["parameters"].([]map[string]any)...)
+
+	paths := map[string]any{
+		"/estimate": map[string]any{
+			"get":  operation("Estimate a swap through a pool", estimateParamsSpec, ref(EstimateResponse{}), estimateExample, estimateErrors),
+			"post": estimatePost,
+		},
+		"/estimate_batch": map[string]any{
+			"post": batchPost,
+		},
+		"/estimate_by_tokens": map[string]any{
+			"get": operation("Estimate a swap, resolving the pool from the factory",
+				[]openAPIParam{srcParam, dstParam, srcAmountParam, {"factory", "Factory to resolve the pool on instead of FACTORY_ADDRESS; its FACTORY_FEES fee is applied", false, "0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2Ac"}},
+				ref(EstimateResponse{}), nil, estimateErrors),
+		},
+		"/estimate_exact_out": map[string]any{
+			"get": operation("Estimate the input needed for an exact output",
+				[]openAPIParam{poolParam, srcParam, dstParam, {"dst_amount", "Desired output in the dst token's base units", true, "6241000000000000"}},
+				ref(EstimateExactOutResponse{}), EstimateExactOutResponse{SrcAmount: "10000000"}, estimateErrors),
+		},
+		"/estimate_route": map[string]any{
+			"get": operation("Estimate a multi-hop swap",
+				[]openAPIParam{
+					{"path", "Comma-separated token addresses from src to dst", true, exampleUSDT + "," + exampleWETH},
+					{"pools", "Comma-separated pair addresses, one per hop", true, exampleUSDTWETHPool},
+					srcAmountParam,
+				},
+				ref(EstimateRouteResponse{}), nil, estimateErrors),
+		},
+		"/quote": map[string]any{
+			"get": operation("Estimate a swap with spot and execution prices",
+				[]openAPIParam{poolParam, srcParam, optionalDstParam, srcAmountParam, feeBpsParam, blockParam, blockTagParam, chainIDParam},
+				ref(QuoteResponse{}), nil, estimateErrors),
+		},
+		"/price": map[string]any{
+			"get": operation("Read a pool's marginal price, independent of trade size",
+				[]openAPIParam{poolParam, srcParam, dstParam, blockParam, blockTagParam, chainIDParam},
+				ref(PriceResponse{}), PriceResponse{Price: "0.000625882914662741", SrcDecimals: 6, DstDecimals: 18}, estimateErrors),
+		},
+		"/trade": map[string]any{
+			"get": operation("Quote buying or selling the pool's base token, token0, for its quote token, token1",
+				[]openAPIParam{
+					poolParam,
+					{"side", "buy to receive exactly amount of the base token, sell to spend exactly amount of it", true, "sell"},
+					{"amount", "Base token amount in base units", true, "1000000000000000000"},
+					blockParam, blockTagParam, chainIDParam,
+				},
+				ref(TradeResponse{}), nil, estimateErrors),
+		},
+		"/estimate_both": map[string]any{
+			"get": operation("Quote selling and buying the same amount of a token from one read of the reserves",
+				[]openAPIParam{
+					poolParam,
+					{"token", "Token whose amount is fixed on both sides; must be one of the pool's tokens", true, exampleWETH},
+					{"amount", "Amount of token to sell, and to buy, in base units", true, "1000000000000000000"},
+					blockParam, blockTagParam, chainIDParam,
+				},
+				ref(BothSidesResponse{}), nil, estimateErrors),
+		},
+		"/pairs": map[string]any{
+			"get": operation("Page through the pairs a factory has created",
+				[]openAPIParam{
+					{"factory", "Factory to list instead of FACTORY_ADDRESS", false, "0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2Ac"},
+					{"offset", "Index of the first pair, in creation order; default 0", false, "1000"},
+					{"limit", fmt.Sprintf("Pairs per page, at most %d; default %d", maxPairsLimit, defaultPairsLimit), false, "50"},
+					{"include_tokens", "Adds each pair's token0 and token1", false, "true"},
+					chainIDParam,
+				},
+				ref(PairsResponse{}), nil, estimateErrors),
+		},
+		"/estimate_best": map[string]any{
+			"get": operation("Estimate a swap through each candidate pool and pick the best",
+				[]openAPIParam{
+					{"pools", fmt.Sprintf("Comma-separated candidate pair addresses, at most %d", maxCandidatePools), true, exampleUSDTWETHPool},
+					srcParam, dstParam, srcAmountParam, chainIDParam,
+				},
+				ref(EstimateBestResponse{}), nil, estimateErrors),
+		},
+		"/max_amount_in": map[string]any{
+			"get": operation("Find the largest input whose price impact stays within a target",
+				[]openAPIParam{
+					poolParam, srcParam, dstParam,
+					{"price_impact", "Target price impact as a percentage, above the pool fee's own impact (0.3 by default) and below 100", true, "1"},
+					blockParam, blockTagParam, chainIDParam,
+				},
+				ref(MaxAmountInResponse{}), nil, estimateErrors),
+		},
+		"/depth": map[string]any{
+			"get": operation("Quote a range of input sizes against one read of the reserves, for depth charts",
+				[]openAPIParam{
+					poolParam, srcParam, dstParam,
+					{"src_amount", "Largest input size, in the src token's base units", true, "10000000000"},
+					{"points", fmt.Sprintf("Number of sizes, at most %d; default %d", maxDepthPoints, defaultDepthPoints), false, "20"},
+					{"scale", "linear (default) steps down by src_amount/points; log halves the size at each point", false, "log"},
+					blockParam, blockTagParam, chainIDParam,
+				},
+				ref(DepthResponse{}), nil, estimateErrors),
+		},
+		"/estimate_split": map[string]any{
+			"get": operation("Split a swap between two pools for the same pair to maximize the combined output",
+				[]openAPIParam{
+					{"pools", "Two comma-separated pair addresses for the same token pair", true, exampleUSDTWETHPool + ",0x06da0fd433C1A5d7a4faa01111c044910A184553"},
+					srcParam, dstParam, srcAmountParam, chainIDParam,
+				},
+				ref(EstimateSplitResponse{}), nil, estimateErrors),
+		},
+		"/estimate_v3": map[string]any{
+			"get": operation("Quote a swap through a Uniswap V3 pool for comparison",
+				[]openAPIParam{srcParam, dstParam, srcAmountParam, {"fee_tier", "V3 fee tier: 100, 500, 3000 (default) or 10000", false, "500"}, chainIDParam},
+				ref(EstimateV3Response{}), nil, estimateErrors),
+		},
+		"/pools/{address}/tokens": map[string]any{
+			"get": poolTokensGet,
+		},
+		"/reserves": map[string]any{
+			"get": operation("Read a pair's raw reserves and tokens",
+				[]openAPIParam{poolParam, blockParam, blockTagParam, chainIDParam},
+				ref(ReservesResponse{}), nil, estimateErrors),
+		},
+		"/health": map[string]any{
+			"get": healthGet,
+		},
+		"/version": map[string]any{
+			"get": operation("Report the running build", nil, ref(VersionResponse{}),
+				VersionResponse{GitCommit: "6ada687", BuildTime: "2024-06-10T12:00:00Z", GoVersion: "go1.23.0"}, nil),
+		},
+		"/live": map[string]any{
+			"get": operation("Check that the process is serving requests", nil, map[string]any{"type": "object"},
+				map[string]string{"status": "ok"}, nil),
+		},
+	}
+
+	// The code enum can't be derived from the string field
+	errorSchema := schemas["ErrorResponse"].(map[string]any)
+	errorSchema["properties"].(map[string]any)["code"] = map[string]any{"type": "string", "enum": errorCodes}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Uniswap V2 Swap Estimator API",
+			"description": "Estimates Uniswap V2 swap amounts from live pool reserves. The /ws/quote WebSocket endpoint takes the same parameters as GET /estimate, without block.",
+			"version":     openAPIVersion,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+}
+
+func jsonContent(schema map[string]any, example any) map[string]any {
+	mediaType := map[string]any{"schema": schema}
+	if example != nil {
+		mediaType["example"] = example
+	}
+	return map[string]any{"application/json": mediaType}
+}
+
+func queryParams(params []openAPIParam) []map[string]any {
+	out := make([]map[string]any, len(params))
+	for i, p := range params {
+		out[i] = map[string]any{
+			"name":        p.name,
+			"in":          "query",
+			"description": p.description,
+			"required":    p.required,
+			"schema":      map[string]any{"type": "string"},
+			"example":     p.example,
+		}
+	}
+	return out
+}
+
+// schemaFor derives a JSON schema from a Go type using its json tags. Fields
+// without omitempty are marked required.
+func schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = schemaFor(field.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return map[string]any{}
+	}
+}
+
+// openAPIHandler serves the spec generated at startup.
+func openAPIHandler() (http.HandlerFunc, error) {
+	spec, err := json.Marshal(buildOpenAPISpec())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal OpenAPI spec: %w", err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	}, nil
+}
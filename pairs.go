@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	defaultPairsLimit = 100
+	maxPairsLimit     = 500
+)
+
+type PairInfo struct {
+	Address string `json:"address"`
+	// Token0 and Token1 are only set when the request asks for include_tokens
+	Token0 string `json:"token0,omitempty"`
+	Token1 string `json:"token1,omitempty"`
+}
+
+type PairsResponse struct {
+	Factory string `json:"factory"`
+	// Total is the factory's allPairsLength
+	Total  uint64     `json:"total"`
+	Offset uint64     `json:"offset"`
+	Pairs  []PairInfo `json:"pairs"`
+}
+
+func (ec *EthereumClient) GetAllPairsLength(ctx context.Context, factoryAddr common.Address) (uint64, error) {
+	data, err := ec.factoryABI.Pack("allPairsLength")
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack allPairsLength call: %w", err)
+	}
+
+	result, err := ec.callContract(ctx, "allPairsLength", factoryAddr, data, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call allPairsLength: %w", err)
+	}
+
+	unpacked, err := ec.factoryABI.Unpack("allPairsLength", result)
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to unpack allPairsLength result: %w", errMalformedResult, err)
+	}
+
+	if len(unpacked) == 0 {
+		return 0, fmt.Errorf("empty allPairsLength result")
+	}
+
+	length, ok := unpacked[0].(*big.Int)
+	if !ok || !length.IsUint64() {
+		return 0, fmt.Errorf("%w: allPairsLength is not a uint64", errMalformedResult)
+	}
+
+	return length.Uint64(), nil
+}
+
+// GetAllPairs returns the factory's pairs at indexes [offset, offset+count),
+// reading them in one Multicall3 call where it is deployed.
+func (ec *EthereumClient) GetAllPairs(ctx context.Context, factoryAddr common.Address, offset, count uint64) ([]common.Address, error) {
+	calls := make([]multicall3Call, count)
+	for i := range calls {
+		data, err := ec.factoryABI.Pack("allPairs", new(big.Int).SetUint64(offset+uint64(i)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack allPairs call: %w", err)
+		}
+		calls[i] = multicall3Call{Target: factoryAddr, CallData: data}
+	}
+
+	results, err := ec.aggregate3(ctx, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]common.Address, count)
+	for i, call := range calls {
+		var result []byte
+		if results == nil {
+			// No code at the multicall address on this chain
+			result, err = ec.callContract(ctx, "allPairs", factoryAddr, call.CallData, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to call allPairs: %w", err)
+			}
+		} else {
+			result = results[i].ReturnData
+		}
+
+		unpacked, err := ec.factoryABI.Unpack("allPairs", result)
+		if err != nil || len(unpacked) == 0 {
+			return nil, fmt.Errorf("%w: failed to unpack allPairs(%d) result", errMalformedResult, offset+uint64(i))
+		}
+		pair, ok := unpacked[0].(common.Address)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast allPairs to common.Address")
+		}
+		pairs[i] = pair
+	}
+
+	return pairs, nil
+}
+
+// factoryCallError reports an address that has no code or doesn't implement
+// allPairs as not found, like a missing pool.
+func factoryCallError(factoryAddr common.Address, err error) error {
+	if isContractFailure(err) {
+		return fmt.Errorf("%w: %s is not a Uniswap V2 factory: %v", ErrPoolNotFound, factoryAddr.Hex(), err)
+	}
+	if isTimeout(err) {
+		return fmt.Errorf("failed to list pairs: %w", err)
+	}
+	return fmt.Errorf("%w: failed to list pairs: %w", ErrRPCFailure, err)
+}
+
+// ListPairs pages through the pairs created by factoryAddr, in creation
+// order. An offset past the end returns an empty page.
+func (se *SwapEstimator) ListPairs(ctx context.Context, factoryAddr common.Address, offset, limit uint64, includeTokens bool) (*PairsResponse, error) {
+	total, err := se.ethClient.GetAllPairsLength(ctx, factoryAddr)
+	if err != nil {
+		return nil, factoryCallError(factoryAddr, err)
+	}
+
+	response := &PairsResponse{Factory: factoryAddr.Hex(), Total: total, Offset: offset, Pairs: []PairInfo{}}
+	if offset >= total {
+		return response, nil
+	}
+
+	pairs, err := se.ethClient.GetAllPairs(ctx, factoryAddr, offset, min(limit, total-offset))
+	if err != nil {
+		return nil, factoryCallError(factoryAddr, err)
+	}
+
+	var tokens [][2]common.Address
+	if includeTokens {
+		tokens, err = se.ethClient.GetPairTokensBatch(ctx, pairs)
+		if err != nil {
+			if isTimeout(err) || errors.Is(err, ErrNotUniswapV2Pair) || errors.Is(err, ErrPoolNotFound) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: failed to read pair tokens: %w", ErrRPCFailure, err)
+		}
+	}
+
+	for i, pair := range pairs {
+		info := PairInfo{Address: pair.Hex()}
+		if includeTokens {
+			info.Token0, info.Token1 = tokens[i][0].Hex(), tokens[i][1].Hex()
+		}
+		response.Pairs = append(response.Pairs, info)
+	}
+
+	return response, nil
+}
+
+func (se *SwapEstimator) pairsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+
+	var (
+		err         error
+		factoryAddr common.Address
+	)
+	if factoryStr := query.Get("factory"); factoryStr != "" {
+		factoryAddr, err = parseAddress("factory", factoryStr)
+		if err != nil {
+			writeRequestError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	var offset uint64
+	if v := query.Get("offset"); v != "" {
+		offset, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, "Invalid offset: must be a non-negative integer")
+			return
+		}
+	}
+
+	limit := uint64(defaultPairsLimit)
+	if v := query.Get("limit"); v != "" {
+		limit, err = strconv.ParseUint(v, 10, 64)
+		if err != nil || limit < 1 || limit > maxPairsLimit {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("Invalid limit: must be an integer between 1 and %d", maxPairsLimit))
+			return
+		}
+	}
+
+	var includeTokens bool
+	if v := query.Get("include_tokens"); v != "" {
+		includeTokens, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, "Invalid include_tokens: must be true or false")
+			return
+		}
+	}
+
+	se, err = se.forChain(query.Get("chain_id"))
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if !errors.Is(err, ErrChainNotConfigured) {
+			slog.ErrorContext(r.Context(), "pair listing failed", "factory", factoryAddr.Hex(), "error", err)
+			status, message = http.StatusInternalServerError, "Failed to connect to chain"
+		}
+		writeError(w, status, errorCode(status, err), message)
+		return
+	}
+
+	// Resolved after chain_id, since each chain has its own factory
+	if factoryAddr == (common.Address{}) {
+		factoryAddr = se.factory
+	}
+	if factoryAddr == (common.Address{}) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "Missing factory: no factory address configured for this chain")
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	response, err := se.ListPairs(ctx, factoryAddr, offset, limit, includeTokens)
+	if err != nil {
+		slog.WarnContext(ctx, "pair listing failed", "factory", factoryAddr.Hex(), "offset", offset, "limit", limit, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
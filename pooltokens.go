@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+)
+
+type TokenInfo struct {
+	Address string `json:"address"`
+	// Symbol and Decimals are only set with include_metadata, and Symbol is
+	// omitted for tokens without symbol()
+	Symbol   string `json:"symbol,omitempty"`
+	Decimals *uint8 `json:"decimals,omitempty"`
+}
+
+type PoolTokensResponse struct {
+	Pool   string    `json:"pool"`
+	Token0 TokenInfo `json:"token0"`
+	Token1 TokenInfo `json:"token1"`
+}
+
+// GetPoolTokens returns a pair's token0 and token1, which are cached after
+// the first lookup.
+func (se *SwapEstimator) GetPoolTokens(ctx context.Context, poolAddr common.Address) (common.Address, common.Address, error) {
+	if err := se.checkPoolAllowed(poolAddr); err != nil {
+		return common.Address{}, common.Address{}, err
+	}
+
+	token0, err := se.ethClient.GetToken0(ctx, poolAddr, nil)
+	if err != nil {
+		return common.Address{}, common.Address{}, pairCallError(poolAddr, "token0", err)
+	}
+
+	token1, err := se.ethClient.GetToken1(ctx, poolAddr, nil)
+	if err != nil {
+		return common.Address{}, common.Address{}, pairCallError(poolAddr, "token1", err)
+	}
+
+	if token0 == (common.Address{}) || token1 == (common.Address{}) {
+		return common.Address{}, common.Address{}, fmt.Errorf("%w: %s reports a zero token address", ErrNotUniswapV2Pair, poolAddr.Hex())
+	}
+
+	return token0, token1, nil
+}
+
+// poolTokensHandler serves /pools/{address}/tokens, which labels a pool in
+// one call: its tokens and, with include_metadata, their symbols and
+// decimals.
+func (se *SwapEstimator) poolTokensHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	poolStr := mux.Vars(r)["address"]
+	poolAddr, err := parseAddress("address", poolStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query := r.URL.Query()
+	includeMetadata := false
+	if v := query.Get("include_metadata"); v != "" {
+		includeMetadata, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, "Invalid include_metadata: must be true or false")
+			return
+		}
+	}
+
+	se, err = se.forChain(query.Get("chain_id"))
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if !errors.Is(err, ErrChainNotConfigured) {
+			slog.ErrorContext(r.Context(), "pool tokens lookup failed", "pool", poolStr, "error", err)
+			status, message = http.StatusInternalServerError, "Failed to connect to chain"
+		}
+		writeError(w, status, errorCode(status, err), message)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	token0, token1, err := se.GetPoolTokens(ctx, poolAddr)
+	if err != nil {
+		slog.WarnContext(ctx, "pool tokens lookup failed", "pool", poolStr, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	response := PoolTokensResponse{
+		Pool:   poolAddr.Hex(),
+		Token0: TokenInfo{Address: token0.Hex()},
+		Token1: TokenInfo{Address: token1.Hex()},
+	}
+
+	if includeMetadata {
+		// Like include_metadata on /estimate, metadata is best effort
+		response.Token0.Symbol, response.Token1.Symbol = se.tokenSymbols(ctx, swapTokens{Src: token0, Dst: token1})
+
+		decimals0, decimals1, err := se.ethClient.GetPairDecimals(ctx, token0, token1)
+		if err != nil {
+			slog.DebugContext(ctx, "decimals lookup failed", "pool", poolStr, "error", err)
+		} else {
+			response.Token0.Decimals, response.Token1.Decimals = &decimals0, &decimals1
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
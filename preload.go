@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxConcurrentPreloads bounds how many pools are preloaded at once so a long
+// PRELOAD_POOLS list doesn't burst the node.
+const maxConcurrentPreloads = 8
+
+// PreloadPools reads each pool's token0 and token1, and its reserves when
+// withReserves is set, so they are cached before the first request needs
+// them. Failures are logged and skipped; it returns how many pools loaded.
+func (se *SwapEstimator) PreloadPools(ctx context.Context, pools []common.Address, withReserves bool) int {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		loaded int
+	)
+	sem := make(chan struct{}, maxConcurrentPreloads)
+
+	for _, pool := range pools {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := se.preloadPool(ctx, pool, withReserves); err != nil {
+				slog.WarnContext(ctx, "pool preload failed", "pool", pool.Hex(), "error", err)
+				return
+			}
+			mu.Lock()
+			loaded++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return loaded
+}
+
+func (se *SwapEstimator) preloadPool(ctx context.Context, pool common.Address, withReserves bool) error {
+	if err := se.checkPoolAllowed(pool); err != nil {
+		return err
+	}
+
+	ctx, cancel := se.withRPCTimeout(ctx)
+	defer cancel()
+
+	if _, err := se.ethClient.GetToken0(ctx, pool, nil); err != nil {
+		return pairCallError(pool, "token0", err)
+	}
+	if _, err := se.ethClient.GetToken1(ctx, pool, nil); err != nil {
+		return pairCallError(pool, "token1", err)
+	}
+
+	if withReserves {
+		if _, err := se.ethClient.GetReserves(ctx, pool, nil); err != nil {
+			return pairCallError(pool, "reserves", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type PriceResponse struct {
+	// Price is dst per src in whole-token units, before fees and slippage
+	Price       string `json:"price"`
+	SrcDecimals uint8  `json:"src_decimals"`
+	DstDecimals uint8  `json:"dst_decimals"`
+}
+
+// MarginalPrice returns the pool's instantaneous price of srcToken in
+// dstToken, reserveOut/reserveIn scaled by the tokens' decimals so it is in
+// whole dst tokens per whole src token. Unlike an estimate it ignores the LP
+// fee and trade size.
+func (se *SwapEstimator) MarginalPrice(ctx context.Context, poolAddr, srcToken, dstToken common.Address, blockNumber *big.Int) (*big.Rat, uint8, uint8, error) {
+	reserves, err := se.getDirectionalReserves(ctx, poolAddr, srcToken, dstToken, blockNumber)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	srcDecimals, dstDecimals, err := se.ethClient.GetPairDecimals(ctx, srcToken, dstToken)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	// (reserveOut / 10^dstDecimals) / (reserveIn / 10^srcDecimals)
+	price := normalizedRatio(reserves.ReserveOut, reserves.ReserveIn, dstDecimals, srcDecimals)
+	return price, srcDecimals, dstDecimals, nil
+}
+
+func (se *SwapEstimator) priceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	poolStr := query.Get("pool")
+	srcStr := query.Get("src")
+	dstStr := query.Get("dst")
+
+	if poolStr == "" || srcStr == "" || dstStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameters: pool, src, dst")
+		return
+	}
+
+	poolAddr, err := parseAddress("pool", poolStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	blockNumber, err := parseBlockParams(query.Get("block"), query.Get("block_tag"))
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	se, err = se.forChain(query.Get("chain_id"))
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if !errors.Is(err, ErrChainNotConfigured) {
+			slog.ErrorContext(r.Context(), "price lookup failed", "pool", poolStr, "error", err)
+			status, message = http.StatusInternalServerError, "Failed to connect to chain"
+		}
+		writeError(w, status, errorCode(status, err), message)
+		return
+	}
+
+	tokens, err := se.resolveSwapTokens(srcStr, dstStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	price, srcDecimals, dstDecimals, err := se.MarginalPrice(ctx, poolAddr, tokens.Src, tokens.Dst, blockNumber)
+	if err != nil {
+		slog.WarnContext(ctx, "price lookup failed", "pool", poolStr, "src", srcStr, "dst", dstStr, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	response := PriceResponse{
+		Price:       formatRat(price, priceDisplayPrecision),
+		SrcDecimals: srcDecimals,
+		DstDecimals: dstDecimals,
+	}
+	json.NewEncoder(w).Encode(response)
+}
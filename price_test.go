@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TestMarginalPriceNormalizesDecimals prices a USDC/WETH-like pool holding
+// 50,000,000 USDC (6 decimals) and 20,000 WETH (18 decimals). Raw reserves
+// are 12 orders of magnitude apart, so a price that skips the decimals is
+// off by 10^12.
+func TestMarginalPriceNormalizesDecimals(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {
+			token0: testToken0,
+			token1: testToken1,
+			reserves: &PoolReserves{
+				Reserve0: bigInt(t, "50000000000000"),
+				Reserve1: bigInt(t, "20000000000000000000000"),
+			},
+		},
+	})
+	chain.decimals = map[common.Address]uint8{testToken0: 6, testToken1: 18}
+	se := NewSwapEstimator(chain)
+
+	tests := []struct {
+		name                     string
+		src, dst                 common.Address
+		srcDecimals, dstDecimals uint8
+		want                     string
+	}{
+		{"WETH in USDC", testToken1, testToken0, 18, 6, "2500"},
+		{"USDC in WETH", testToken0, testToken1, 6, 18, "0.0004"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, srcDecimals, dstDecimals, err := se.MarginalPrice(context.Background(), testPool, tt.src, tt.dst, nil)
+			if err != nil {
+				t.Fatalf("MarginalPrice: %v", err)
+			}
+			if srcDecimals != tt.srcDecimals || dstDecimals != tt.dstDecimals {
+				t.Errorf("decimals = %d/%d, want %d/%d", srcDecimals, dstDecimals, tt.srcDecimals, tt.dstDecimals)
+			}
+			if got := formatRat(price, priceDisplayPrecision); got != tt.want {
+				t.Errorf("price = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
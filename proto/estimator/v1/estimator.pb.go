@@ -0,0 +1,216 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.5
+// 	protoc        (unknown)
+// source: proto/estimator/v1/estimator.proto
+
+package estimatorv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EstimateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Addresses are 0x-prefixed hex strings, as in the REST API; src and dst
+	// may also be ETH
+	Pool string `protobuf:"bytes,1,opt,name=pool,proto3" json:"pool,omitempty"`
+	Src  string `protobuf:"bytes,2,opt,name=src,proto3" json:"src,omitempty"`
+	Dst  string `protobuf:"bytes,3,opt,name=dst,proto3" json:"dst,omitempty"`
+	// src_amount is a base-10 integer in the src token's base units
+	SrcAmount     string `protobuf:"bytes,4,opt,name=src_amount,json=srcAmount,proto3" json:"src_amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EstimateRequest) Reset() {
+	*x = EstimateRequest{}
+	mi := &file_proto_estimator_v1_estimator_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EstimateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EstimateRequest) ProtoMessage() {}
+
+func (x *EstimateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_estimator_v1_estimator_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EstimateRequest.ProtoReflect.Descriptor instead.
+func (*EstimateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_estimator_v1_estimator_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EstimateRequest) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+func (x *EstimateRequest) GetSrc() string {
+	if x != nil {
+		return x.Src
+	}
+	return ""
+}
+
+func (x *EstimateRequest) GetDst() string {
+	if x != nil {
+		return x.Dst
+	}
+	return ""
+}
+
+func (x *EstimateRequest) GetSrcAmount() string {
+	if x != nil {
+		return x.SrcAmount
+	}
+	return ""
+}
+
+type EstimateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DstAmount     string                 `protobuf:"bytes,1,opt,name=dst_amount,json=dstAmount,proto3" json:"dst_amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EstimateResponse) Reset() {
+	*x = EstimateResponse{}
+	mi := &file_proto_estimator_v1_estimator_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EstimateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EstimateResponse) ProtoMessage() {}
+
+func (x *EstimateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_estimator_v1_estimator_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EstimateResponse.ProtoReflect.Descriptor instead.
+func (*EstimateResponse) Descriptor() ([]byte, []int) {
+	return file_proto_estimator_v1_estimator_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *EstimateResponse) GetDstAmount() string {
+	if x != nil {
+		return x.DstAmount
+	}
+	return ""
+}
+
+var File_proto_estimator_v1_estimator_proto protoreflect.FileDescriptor
+
+var file_proto_estimator_v1_estimator_proto_rawDesc = string([]byte{
+	0x0a, 0x22, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x6f,
+	0x72, 0x2f, 0x76, 0x31, 0x2f, 0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0c, 0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x6f, 0x72, 0x2e,
+	0x76, 0x31, 0x22, 0x68, 0x0a, 0x0f, 0x45, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x6f, 0x6c, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x6f, 0x6f, 0x6c, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x72, 0x63,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x73, 0x72, 0x63, 0x12, 0x10, 0x0a, 0x03, 0x64,
+	0x73, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x64, 0x73, 0x74, 0x12, 0x1d, 0x0a,
+	0x0a, 0x73, 0x72, 0x63, 0x5f, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x73, 0x72, 0x63, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x31, 0x0a, 0x10,
+	0x45, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x1d, 0x0a, 0x0a, 0x64, 0x73, 0x74, 0x5f, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x64, 0x73, 0x74, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x32,
+	0x56, 0x0a, 0x09, 0x45, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x49, 0x0a, 0x08,
+	0x45, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x12, 0x1d, 0x2e, 0x65, 0x73, 0x74, 0x69, 0x6d,
+	0x61, 0x74, 0x6f, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x65, 0x73, 0x74, 0x69, 0x6d, 0x61,
+	0x74, 0x6f, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x35, 0x5a, 0x33, 0x75, 0x6e, 0x69, 0x73, 0x77,
+	0x61, 0x70, 0x2d, 0x76, 0x32, 0x2d, 0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x6f, 0x72, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x6f, 0x72, 0x2f,
+	0x76, 0x31, 0x3b, 0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x6f, 0x72, 0x76, 0x31, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+})
+
+var (
+	file_proto_estimator_v1_estimator_proto_rawDescOnce sync.Once
+	file_proto_estimator_v1_estimator_proto_rawDescData []byte
+)
+
+func file_proto_estimator_v1_estimator_proto_rawDescGZIP() []byte {
+	file_proto_estimator_v1_estimator_proto_rawDescOnce.Do(func() {
+		file_proto_estimator_v1_estimator_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_estimator_v1_estimator_proto_rawDesc), len(file_proto_estimator_v1_estimator_proto_rawDesc)))
+	})
+	return file_proto_estimator_v1_estimator_proto_rawDescData
+}
+
+var file_proto_estimator_v1_estimator_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_estimator_v1_estimator_proto_goTypes = []any{
+	(*EstimateRequest)(nil),  // 0: estimator.v1.EstimateRequest
+	(*EstimateResponse)(nil), // 1: estimator.v1.EstimateResponse
+}
+var file_proto_estimator_v1_estimator_proto_depIdxs = []int32{
+	0, // 0: estimator.v1.Estimator.Estimate:input_type -> estimator.v1.EstimateRequest
+	1, // 1: estimator.v1.Estimator.Estimate:output_type -> estimator.v1.EstimateResponse
+	1, // [1:2] is the sub-list for method output_type
+	0, // [0:1] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_proto_estimator_v1_estimator_proto_init() }
+func file_proto_estimator_v1_estimator_proto_init() {
+	if File_proto_estimator_v1_estimator_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_estimator_v1_estimator_proto_rawDesc), len(file_proto_estimator_v1_estimator_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_estimator_v1_estimator_proto_goTypes,
+		DependencyIndexes: file_proto_estimator_v1_estimator_proto_depIdxs,
+		MessageInfos:      file_proto_estimator_v1_estimator_proto_msgTypes,
+	}.Build()
+	File_proto_estimator_v1_estimator_proto = out.File
+	file_proto_estimator_v1_estimator_proto_goTypes = nil
+	file_proto_estimator_v1_estimator_proto_depIdxs = nil
+}
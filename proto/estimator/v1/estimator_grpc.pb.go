@@ -0,0 +1,135 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: proto/estimator/v1/estimator.proto
+
+package estimatorv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Estimator_Estimate_FullMethodName = "/estimator.v1.Estimator/Estimate"
+)
+
+// EstimatorClient is the client API for Estimator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Estimator mirrors the REST /estimate endpoint for service-to-service calls.
+type EstimatorClient interface {
+	// Estimate returns the output of swapping src_amount of src for dst in pool.
+	// Estimator errors map to status codes the same way the REST API maps them
+	// to HTTP statuses: INVALID_ARGUMENT (400), PERMISSION_DENIED (403),
+	// NOT_FOUND (404), FAILED_PRECONDITION (422), UNAVAILABLE (502, 503),
+	// DEADLINE_EXCEEDED (504) and INTERNAL (500).
+	Estimate(ctx context.Context, in *EstimateRequest, opts ...grpc.CallOption) (*EstimateResponse, error)
+}
+
+type estimatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEstimatorClient(cc grpc.ClientConnInterface) EstimatorClient {
+	return &estimatorClient{cc}
+}
+
+func (c *estimatorClient) Estimate(ctx context.Context, in *EstimateRequest, opts ...grpc.CallOption) (*EstimateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EstimateResponse)
+	err := c.cc.Invoke(ctx, Estimator_Estimate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EstimatorServer is the server API for Estimator service.
+// All implementations must embed UnimplementedEstimatorServer
+// for forward compatibility.
+//
+// Estimator mirrors the REST /estimate endpoint for service-to-service calls.
+type EstimatorServer interface {
+	// Estimate returns the output of swapping src_amount of src for dst in pool.
+	// Estimator errors map to status codes the same way the REST API maps them
+	// to HTTP statuses: INVALID_ARGUMENT (400), PERMISSION_DENIED (403),
+	// NOT_FOUND (404), FAILED_PRECONDITION (422), UNAVAILABLE (502, 503),
+	// DEADLINE_EXCEEDED (504) and INTERNAL (500).
+	Estimate(context.Context, *EstimateRequest) (*EstimateResponse, error)
+	mustEmbedUnimplementedEstimatorServer()
+}
+
+// UnimplementedEstimatorServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedEstimatorServer struct{}
+
+func (UnimplementedEstimatorServer) Estimate(context.Context, *EstimateRequest) (*EstimateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Estimate not implemented")
+}
+func (UnimplementedEstimatorServer) mustEmbedUnimplementedEstimatorServer() {}
+func (UnimplementedEstimatorServer) testEmbeddedByValue()                   {}
+
+// UnsafeEstimatorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EstimatorServer will
+// result in compilation errors.
+type UnsafeEstimatorServer interface {
+	mustEmbedUnimplementedEstimatorServer()
+}
+
+func RegisterEstimatorServer(s grpc.ServiceRegistrar, srv EstimatorServer) {
+	// If the following call pancis, it indicates UnimplementedEstimatorServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Estimator_ServiceDesc, srv)
+}
+
+func _Estimator_Estimate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EstimateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EstimatorServer).Estimate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Estimator_Estimate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EstimatorServer).Estimate(ctx, req.(*EstimateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Estimator_ServiceDesc is the grpc.ServiceDesc for Estimator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Estimator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "estimator.v1.Estimator",
+	HandlerType: (*EstimatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Estimate",
+			Handler:    _Estimator_Estimate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/estimator/v1/estimator.proto",
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// priceDisplayPrecision is the number of decimal places used when rendering
+// prices, before trailing zeros are trimmed.
+const priceDisplayPrecision = 18
+
+type QuoteResponse struct {
+	SpotPrice      string `json:"spot_price"`
+	ExecutionPrice string `json:"execution_price"`
+	DstAmount      string `json:"dst_amount"`
+	ReserveIn      string `json:"reserve_in"`
+	ReserveOut     string `json:"reserve_out"`
+}
+
+// normalizedRatio returns (num / 10^numDecimals) / (den / 10^denDecimals),
+// i.e. the ratio of two raw token amounts expressed in whole-token units.
+func normalizedRatio(num, den *big.Int, numDecimals, denDecimals uint8) *big.Rat {
+	if den.Sign() == 0 {
+		return new(big.Rat)
+	}
+
+	scaledNum := new(big.Int).Mul(num, pow10(denDecimals))
+	scaledDen := new(big.Int).Mul(den, pow10(numDecimals))
+	return new(big.Rat).SetFrac(scaledNum, scaledDen)
+}
+
+func pow10(n uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// formatRat renders r with up to prec decimal places, trimming trailing zeros.
+func formatRat(r *big.Rat, prec int) string {
+	s := r.FloatString(prec)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func (se *SwapEstimator) quoteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	req := EstimateRequest{
+		Pool:      query.Get("pool"),
+		Src:       query.Get("src"),
+		Dst:       query.Get("dst"),
+		SrcAmount: query.Get("src_amount"),
+		FeeBps:    query.Get("fee_bps"),
+		Block:     query.Get("block"),
+		BlockTag:  query.Get("block_tag"),
+		ChainID:   query.Get("chain_id"),
+		NodeURL:   query.Get("node_url"),
+	}
+
+	params, status, err := se.parseEstimateRequest(req)
+	if err != nil {
+		if status >= http.StatusInternalServerError {
+			slog.ErrorContext(r.Context(), "quote failed", "pool", req.Pool, "error", err)
+			err = errors.New("Failed to connect to chain")
+		}
+		writeRequestError(w, status, err)
+		return
+	}
+	defer params.Close()
+	se = params.estimator
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	opts := params.opts
+	if params.inferDst {
+		opts.PoolState, err = se.inferDst(ctx, params)
+		if err != nil {
+			writeEstimateError(w, err)
+			return
+		}
+	}
+
+	estimate, err := se.EstimateSwapWithOptions(ctx, params.pool, params.tokens.Src, params.tokens.Dst, params.srcAmount, opts)
+	if err != nil {
+		slog.WarnContext(ctx, "quote failed", "pool", req.Pool, "src", req.Src, "dst", req.Dst, "src_amount", req.SrcAmount, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	srcDecimals, dstDecimals, err := se.ethClient.GetPairDecimals(ctx, params.tokens.Src, params.tokens.Dst)
+	if err != nil {
+		slog.ErrorContext(ctx, "quote failed", "pool", req.Pool, "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternalError, "Failed to fetch token decimals")
+		return
+	}
+
+	response := QuoteResponse{
+		SpotPrice:      formatRat(normalizedRatio(estimate.ReserveOut, estimate.ReserveIn, dstDecimals, srcDecimals), priceDisplayPrecision),
+		ExecutionPrice: formatRat(normalizedRatio(estimate.AmountOut, params.srcAmount, dstDecimals, srcDecimals), priceDisplayPrecision),
+		DstAmount:      estimate.AmountOut.String(),
+		ReserveIn:      estimate.ReserveIn.String(),
+		ReserveOut:     estimate.ReserveOut.String(),
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Uniswap V3 QuoterV2, deployed at the same address on mainnet, Arbitrum,
+// Optimism and Polygon
+var defaultQuoterV3Address = common.HexToAddress("0x61fFE014bA17989E743c5F6cB21bF9697530B21e")
+
+const defaultV3FeeTier = 3000
+
+// v3FeeTiers are the fee tiers, in hundredths of a basis point, that V3
+// factories enable by default.
+var v3FeeTiers = []uint32{100, 500, 3000, 10000}
+
+// quoteExactInputSingleParams mirrors QuoterV2's QuoteExactInputSingleParams
+// struct; field names must match the ABI components.
+type quoteExactInputSingleParams struct {
+	TokenIn           common.Address
+	TokenOut          common.Address
+	AmountIn          *big.Int
+	Fee               *big.Int
+	SqrtPriceLimitX96 *big.Int
+}
+
+// V3Quote is the result of a QuoterV2 quoteExactInputSingle call.
+type V3Quote struct {
+	AmountOut               *big.Int
+	SqrtPriceX96After       *big.Int
+	InitializedTicksCrossed uint32
+	GasEstimate             *big.Int
+}
+
+type EstimateV3Response struct {
+	DstAmount         string `json:"dst_amount"`
+	FeeTier           uint32 `json:"fee_tier"`
+	SqrtPriceX96After string `json:"sqrt_price_x96_after"`
+	TicksCrossed      uint32 `json:"ticks_crossed"`
+	// GasEstimate is the quoter's estimate of the swap's gas cost
+	GasEstimate string `json:"gas_estimate"`
+	WrapsETH    bool   `json:"wraps_eth,omitempty"`
+	UnwrapsWETH bool   `json:"unwraps_weth,omitempty"`
+}
+
+// QuoteExactInputSingleV3 asks quoter how much tokenOut the V3 pool for
+// tokenIn/tokenOut at feeTier returns for amountIn. The quoter reverts when
+// the pool doesn't exist.
+func (ec *EthereumClient) QuoteExactInputSingleV3(ctx context.Context, quoter, tokenIn, tokenOut common.Address, amountIn *big.Int, feeTier uint32) (*V3Quote, error) {
+	data, err := ec.quoterV3ABI.Pack("quoteExactInputSingle", quoteExactInputSingleParams{
+		TokenIn:           tokenIn,
+		TokenOut:          tokenOut,
+		AmountIn:          amountIn,
+		Fee:               new(big.Int).SetUint64(uint64(feeTier)),
+		SqrtPriceLimitX96: new(big.Int),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack quoteExactInputSingle call: %w", err)
+	}
+
+	result, err := ec.callContract(ctx, "quoteExactInputSingle", quoter, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call quoteExactInputSingle: %w", err)
+	}
+
+	unpacked, err := ec.quoterV3ABI.Unpack("quoteExactInputSingle", result)
+	if err != nil || len(unpacked) != 4 {
+		return nil, fmt.Errorf("%w: failed to unpack quoteExactInputSingle result", errMalformedResult)
+	}
+
+	return &V3Quote{
+		AmountOut:               abi.ConvertType(unpacked[0], new(big.Int)).(*big.Int),
+		SqrtPriceX96After:       abi.ConvertType(unpacked[1], new(big.Int)).(*big.Int),
+		InitializedTicksCrossed: *abi.ConvertType(unpacked[2], new(uint32)).(*uint32),
+		GasEstimate:             abi.ConvertType(unpacked[3], new(big.Int)).(*big.Int),
+	}, nil
+}
+
+func (se *SwapEstimator) SetQuoterV3(quoterAddr common.Address) {
+	se.quoterV3 = quoterAddr
+}
+
+// EstimateSwapV3 quotes srcToken -> dstToken through the V3 pool at feeTier,
+// for comparison with the V2 estimate.
+func (se *SwapEstimator) EstimateSwapV3(ctx context.Context, srcToken, dstToken common.Address, srcAmount *big.Int, feeTier uint32) (*V3Quote, error) {
+	quote, err := se.ethClient.QuoteExactInputSingleV3(ctx, se.quoterV3, srcToken, dstToken, srcAmount, feeTier)
+	if err != nil {
+		if isTimeout(err) {
+			return nil, fmt.Errorf("failed to quote V3 swap: %w", err)
+		}
+		if isContractFailure(err) {
+			return nil, fmt.Errorf("%w: no V3 pool for %s/%s at fee tier %d, or quoter %s unavailable", ErrPoolNotFound, srcToken.Hex(), dstToken.Hex(), feeTier, se.quoterV3.Hex())
+		}
+		return nil, fmt.Errorf("%w: failed to quote V3 swap: %w", ErrRPCFailure, err)
+	}
+
+	return quote, nil
+}
+
+func parseV3FeeTier(s string) (uint32, error) {
+	if s == "" {
+		return defaultV3FeeTier, nil
+	}
+
+	feeTier, err := strconv.ParseUint(s, 10, 32)
+	if err == nil {
+		for _, tier := range v3FeeTiers {
+			if uint32(feeTier) == tier {
+				return tier, nil
+			}
+		}
+	}
+
+	return 0, errors.New("Invalid fee_tier: must be one of 100, 500, 3000, 10000")
+}
+
+// estimateV3Handler quotes the same src/dst/src_amount as /estimate_by_tokens
+// against Uniswap V3, so clients can compare venues.
+func (se *SwapEstimator) estimateV3Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	srcStr := query.Get("src")
+	dstStr := query.Get("dst")
+	srcAmountStr := query.Get("src_amount")
+
+	if srcStr == "" || dstStr == "" || srcAmountStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameters: src, dst, src_amount")
+		return
+	}
+
+	feeTier, err := parseV3FeeTier(query.Get("fee_tier"))
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	se, err = se.forChain(query.Get("chain_id"))
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if !errors.Is(err, ErrChainNotConfigured) {
+			slog.ErrorContext(r.Context(), "V3 estimate failed", "src", srcStr, "dst", dstStr, "error", err)
+			status, message = http.StatusInternalServerError, "Failed to connect to chain"
+		}
+		writeError(w, status, errorCode(status, err), message)
+		return
+	}
+
+	if se.quoterV3 == (common.Address{}) {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "V3 quotes are not supported on this chain: no quoter address configured")
+		return
+	}
+
+	tokens, err := se.resolveSwapTokens(srcStr, dstStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	srcAmount, err := se.parseSrcAmount(srcAmountStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	quote, err := se.EstimateSwapV3(ctx, tokens.Src, tokens.Dst, srcAmount, feeTier)
+	if err != nil {
+		slog.WarnContext(ctx, "V3 estimate failed", "src", srcStr, "dst", dstStr, "src_amount", srcAmountStr, "fee_tier", feeTier, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	response := EstimateV3Response{
+		DstAmount:         quote.AmountOut.String(),
+		FeeTier:           feeTier,
+		SqrtPriceX96After: quote.SqrtPriceX96After.String(),
+		TicksCrossed:      quote.InitializedTicksCrossed,
+		GasEstimate:       quote.GasEstimate.String(),
+		WrapsETH:          tokens.WrapSrc,
+		UnwrapsWETH:       tokens.UnwrapDst,
+	}
+	json.NewEncoder(w).Encode(response)
+}
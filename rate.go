@@ -0,0 +1,18 @@
+package main
+
+import "math/big"
+
+// ExchangeRate is dst_amount/src_amount as an exact fraction in lowest terms,
+// both in base units.
+type ExchangeRate struct {
+	Numerator   string `json:"numerator"`
+	Denominator string `json:"denominator"`
+}
+
+func exchangeRate(amountIn, amountOut *big.Int) *ExchangeRate {
+	rate := new(big.Rat).SetFrac(amountOut, amountIn)
+	return &ExchangeRate{
+		Numerator:   rate.Num().String(),
+		Denominator: rate.Denom().String(),
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestExchangeRate(t *testing.T) {
+	tests := []struct {
+		name                   string
+		amountIn, amountOut    string
+		numerator, denominator string
+	}{
+		{"reduced to lowest terms", "1000000000000000000", "6241000000000000000", "6241", "1000"},
+		{"whole rate", "1000", "6241000", "6241", "1"},
+		{"rate below one", "3000000", "1000", "1", "3000"},
+		{"coprime amounts", "1000000000000000000", "1974316068794122597", "1974316068794122597", "1000000000000000000"},
+		{"zero output", "1000", "0", "0", "1"},
+		{
+			"beyond uint256",
+			"340282366920938463463374607431768211457",
+			"115792089237316195423570985008687907853269984665640564039457584007913129639937",
+			"115792089237316195423570985008687907853269984665640564039457584007913129639937",
+			"340282366920938463463374607431768211457",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amountIn, amountOut := bigInt(t, tt.amountIn), bigInt(t, tt.amountOut)
+			got := exchangeRate(amountIn, amountOut)
+			if got.Numerator != tt.numerator || got.Denominator != tt.denominator {
+				t.Fatalf("exchangeRate = %s/%s, want %s/%s", got.Numerator, got.Denominator, tt.numerator, tt.denominator)
+			}
+
+			// The fraction is exact: amountIn·numerator = amountOut·denominator
+			lhs := new(big.Int).Mul(amountIn, bigInt(t, got.Numerator))
+			rhs := new(big.Int).Mul(amountOut, bigInt(t, got.Denominator))
+			if lhs.Cmp(rhs) != 0 {
+				t.Errorf("%s·%s != %s·%s", tt.amountIn, got.Numerator, tt.amountOut, got.Denominator)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultRateLimitBurst = 20
+
+	limiterCleanupInterval = time.Minute
+	limiterIdleTimeout     = 3 * time.Minute
+)
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipRateLimiter keeps a token bucket per client IP. Buckets that have been
+// idle for limiterIdleTimeout are dropped so the map can't grow unbounded.
+type ipRateLimiter struct {
+	mu      sync.Mutex
+	clients map[string]*clientLimiter
+	rps     rate.Limit
+	burst   int
+	done    chan struct{}
+}
+
+func newIPRateLimiter(rps float64, burst int) *ipRateLimiter {
+	rl := &ipRateLimiter{
+		clients: make(map[string]*clientLimiter),
+		rps:     rate.Limit(rps),
+		burst:   burst,
+		done:    make(chan struct{}),
+	}
+	go rl.cleanupLoop()
+	return rl
+}
+
+func (rl *ipRateLimiter) get(ip string) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	c, ok := rl.clients[ip]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(rl.rps, rl.burst)}
+		rl.clients[ip] = c
+	}
+	c.lastSeen = time.Now()
+	return c.limiter
+}
+
+// reserve takes a token from ip's bucket. It returns 0 when the request may
+// go ahead, and otherwise how long the client should wait, taking nothing.
+func (rl *ipRateLimiter) reserve(ip string) time.Duration {
+	reservation := rl.get(ip).Reserve()
+	delay := reservation.Delay()
+	if delay > 0 {
+		reservation.Cancel()
+	}
+	return delay
+}
+
+func (rl *ipRateLimiter) cleanupLoop() {
+	ticker := time.NewTicker(limiterCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rl.done:
+			return
+		case <-ticker.C:
+			rl.mu.Lock()
+			for ip, c := range rl.clients {
+				if time.Since(c.lastSeen) > limiterIdleTimeout {
+					delete(rl.clients, ip)
+				}
+			}
+			rl.mu.Unlock()
+		}
+	}
+}
+
+func (rl *ipRateLimiter) Close() {
+	close(rl.done)
+}
+
+// Middleware rejects requests over the client's rate with 429. Health, liveness
+// and metrics endpoints are exempt so probes and scrapers are never throttled.
+func (rl *ipRateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" || r.URL.Path == "/live" || r.URL.Path == "/metrics" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if delay := rl.reserve(clientIP(r)); delay > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, CodeRateLimited, "Rate limit exceeded")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP uses the connection's remote address. X-Forwarded-For is ignored
+// because it is trivially spoofable without a trusted proxy in front.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
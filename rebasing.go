@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/big"
+)
+
+const (
+	// rebaseLookbackBlocks is how far back check_rebasing compares reserves
+	rebaseLookbackBlocks = 10
+	// rebaseGrowthThresholdBps is how much faster one reserve must grow than
+	// the other before the pool is flagged
+	rebaseGrowthThresholdBps = 10
+)
+
+// reserveGrowthBps returns how much a reserve grew from before to after, in
+// basis points. It is negative when the reserve shrank, and clamped to
+// math.MaxInt64 when a tiny reserve grew too much to fit.
+func reserveGrowthBps(before, after *big.Int) int64 {
+	if before.Sign() == 0 {
+		return 0
+	}
+	growth := new(big.Int).Sub(after, before)
+	growth.Mul(growth, big.NewInt(10000))
+	growth.Quo(growth, before)
+	// Reserves are uint112, so growth from 1 wei can be far past int64
+	if !growth.IsInt64() {
+		return math.MaxInt64
+	}
+	return growth.Int64()
+}
+
+// looksRebasing reports whether the change between two reserve snapshots
+// looks like one token's balance grew on its own. A swap moves the reserves in
+// opposite directions and a mint or burn moves both by the same proportion,
+// so neither is flagged; a reflection or rebase that is synced into the pool
+// grows one side while the other stays put.
+func looksRebasing(before, after *PoolReserves) bool {
+	growth0 := reserveGrowthBps(before.Reserve0, after.Reserve0)
+	growth1 := reserveGrowthBps(before.Reserve1, after.Reserve1)
+	if growth0 < 0 || growth1 < 0 {
+		return false
+	}
+	return growth0-growth1 > rebaseGrowthThresholdBps || growth1-growth0 > rebaseGrowthThresholdBps
+}
+
+// CheckRebasing compares the pool's reserves at blockNumber with those
+// rebaseLookbackBlocks earlier. It is a heuristic: activity of several kinds
+// within the window can hide a rebase or mimic one.
+func (se *SwapEstimator) CheckRebasing(ctx context.Context, params *estimateParams) (bool, error) {
+	current, err := se.ethClient.GetBlockNumber(ctx, params.opts.BlockNumber)
+	if err != nil {
+		return false, err
+	}
+	if current < rebaseLookbackBlocks {
+		return false, nil
+	}
+
+	after, err := se.ethClient.GetReserves(ctx, params.pool, new(big.Int).SetUint64(current))
+	if err != nil {
+		return false, err
+	}
+	before, err := se.ethClient.GetReserves(ctx, params.pool, new(big.Int).SetUint64(current-rebaseLookbackBlocks))
+	if err != nil {
+		return false, err
+	}
+
+	return looksRebasing(before, after), nil
+}
+
+// checkRebasing sets possible_rebasing on the response. A failed check, for
+// example on a node without the older state, is reported as a warning rather
+// than failing the estimate.
+func (se *SwapEstimator) checkRebasing(ctx context.Context, params *estimateParams, response *EstimateResponse) {
+	possibleRebasing, err := se.CheckRebasing(ctx, params)
+	if err != nil {
+		slog.DebugContext(ctx, "rebasing check failed", "pool", params.pool.Hex(), "error", err)
+		response.Warnings = append(response.Warnings, fmt.Sprintf("Could not compare reserves with %d blocks ago to check for rebasing", rebaseLookbackBlocks))
+		return
+	}
+	response.PossibleRebasing = &possibleRebasing
+}
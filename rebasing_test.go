@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestReserveGrowthBps(t *testing.T) {
+	tests := []struct {
+		before, after int64
+		want          int64
+	}{
+		{1000, 1000, 0},
+		{1000, 1010, 100},
+		{1000, 990, -100},
+		{10000, 10001, 1},
+		// Growth is truncated toward zero
+		{30000, 30002, 0},
+		{0, 1000, 0},
+	}
+
+	for _, tt := range tests {
+		if got := reserveGrowthBps(big.NewInt(tt.before), big.NewInt(tt.after)); got != tt.want {
+			t.Errorf("reserveGrowthBps(%d, %d) = %d, want %d", tt.before, tt.after, got, tt.want)
+		}
+	}
+}
+
+// TestReserveGrowthBpsClamps grows a 1 wei reserve to 2^100, which is
+// 10^34 bps and would wrap if converted to int64 directly.
+func TestReserveGrowthBpsClamps(t *testing.T) {
+	after := new(big.Int).Lsh(big.NewInt(1), 100)
+	if got := reserveGrowthBps(big.NewInt(1), after); got != math.MaxInt64 {
+		t.Errorf("reserveGrowthBps(1, 2^100) = %d, want %d", got, int64(math.MaxInt64))
+	}
+}
+
+func TestLooksRebasing(t *testing.T) {
+	reserves := func(reserve0, reserve1 int64) *PoolReserves {
+		return &PoolReserves{Reserve0: big.NewInt(reserve0), Reserve1: big.NewInt(reserve1)}
+	}
+
+	tests := []struct {
+		name          string
+		before, after *PoolReserves
+		want          bool
+	}{
+		{"unchanged", reserves(1000000, 2000000), reserves(1000000, 2000000), false},
+		{"swap", reserves(1000000, 2000000), reserves(1100000, 1820000), false},
+		{"mint", reserves(1000000, 2000000), reserves(1500000, 3000000), false},
+		{"burn", reserves(1000000, 2000000), reserves(500000, 1000000), false},
+		{"token0 grew on its own", reserves(1000000, 2000000), reserves(1050000, 2000000), true},
+		{"token1 grew on its own", reserves(1000000, 2000000), reserves(1000000, 2100000), true},
+		{"growth at the threshold", reserves(1000000, 2000000), reserves(1001000, 2000000), false},
+		{"growth just past the threshold", reserves(1000000, 2000000), reserves(1001100, 2000000), true},
+		{"empty pool seeded", reserves(0, 0), reserves(1000000, 2000000), false},
+		{"1 wei reserve grew to 2^100", reserves(1, 2000000), &PoolReserves{Reserve0: new(big.Int).Lsh(big.NewInt(1), 100), Reserve1: big.NewInt(2000000)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := looksRebasing(tt.before, tt.after); got != tt.want {
+				t.Errorf("looksRebasing = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
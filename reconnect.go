@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// conn returns the connection of the first healthy endpoint. Callers should
+// pass it back to checkConnection so a broken connection is replaced.
+func (ec *EthereumClient) conn() *ethclient.Client {
+	return ec.primaryEndpoint().conn()
+}
+
+// checkConnection re-dials the endpoint that failed belongs to when err
+// shows the connection is broken, and skips that endpoint for a while.
+func (ec *EthereumClient) checkConnection(failed *ethclient.Client, err error) {
+	if err == nil || !isConnectionError(err) {
+		return
+	}
+
+	for _, ep := range ec.endpoints {
+		if ep.conn() == failed {
+			ec.markDown(ep, err)
+			ep.checkConnection(failed, err)
+			return
+		}
+	}
+}
+
+func (ep *nodeEndpoint) conn() *ethclient.Client {
+	ep.mu.RLock()
+	defer ep.mu.RUnlock()
+	return ep.client
+}
+
+// checkConnection re-dials the node when err shows that failed, the
+// connection a call was made on, is broken. Concurrent callers that hit the
+// same broken connection only re-dial once.
+func (ep *nodeEndpoint) checkConnection(failed *ethclient.Client, err error) {
+	if err == nil || !isConnectionError(err) {
+		return
+	}
+
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	if ep.client != failed || ep.closed {
+		return
+	}
+
+	client, dialErr := ethclient.Dial(ep.url)
+	if dialErr != nil {
+		slog.Warn("Failed to reconnect to Ethereum node", "endpoint", ep.index, "error", dialErr)
+		return
+	}
+
+	slog.Warn("Reconnected to Ethereum node after connection error", "endpoint", ep.index, "error", err)
+	ep.client = client
+	failed.Close()
+}
+
+// isConnectionError reports whether err means the connection to the node
+// itself failed, as opposed to the node rejecting or reverting the call.
+func isConnectionError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, rpc.ErrClientQuit) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "connection refused") ||
+		strings.Contains(msg, "broken pipe") ||
+		strings.Contains(msg, "use of closed network connection")
+}
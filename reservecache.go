@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxWatchedPairs caps the Sync subscriptions held open per node; pairs
+// beyond it are cached with TTL expiry only.
+const maxWatchedPairs = 256
+
+type cachedReserves struct {
+	reserves  *PoolReserves
+	fetchedAt time.Time
+}
+
+// reservesCache holds the latest reserves of recently quoted pairs for up to
+// ttl. Each cached pair is also watched for Sync events, which evict it at
+// once so a large swap doesn't leave a stale quote for the rest of the TTL.
+// When the node can't push logs the cache degrades to TTL expiry alone.
+type reservesCache struct {
+	ttl time.Duration
+
+	// ctx ends every Sync subscription when the client is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	entries map[common.Address]cachedReserves
+	// versions is bumped on every eviction so a fetch that raced a Sync
+	// event doesn't store what it read
+	versions map[common.Address]uint64
+	watched  map[common.Address]bool
+	// ttlOnly is set once the node rejects log subscriptions
+	ttlOnly bool
+}
+
+func newReservesCache(ttl time.Duration) *reservesCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &reservesCache{
+		ttl:      ttl,
+		ctx:      ctx,
+		cancel:   cancel,
+		entries:  make(map[common.Address]cachedReserves),
+		versions: make(map[common.Address]uint64),
+		watched:  make(map[common.Address]bool),
+	}
+}
+
+// SetReservesCacheTTL caches the latest reserves of each pair for ttl. A ttl
+// of 0 disables the cache.
+func (ec *EthereumClient) SetReservesCacheTTL(ttl time.Duration) {
+	if ec.reservesCache != nil {
+		ec.reservesCache.cancel()
+		ec.reservesCache = nil
+	}
+	if ttl > 0 {
+		ec.reservesCache = newReservesCache(ttl)
+	}
+}
+
+// get returns the cached reserves for pair, if fresh, along with the version
+// to pass to put after fetching them.
+func (rc *reservesCache) get(pair common.Address) (*PoolReserves, uint64) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	entry, ok := rc.entries[pair]
+	if ok && time.Since(entry.fetchedAt) < rc.ttl {
+		return entry.reserves, 0
+	}
+	delete(rc.entries, pair)
+	return nil, rc.versions[pair]
+}
+
+// put caches reserves unless pair was evicted since version was read, and
+// reports whether pair needs a Sync subscription.
+func (rc *reservesCache) put(pair common.Address, reserves *PoolReserves, version uint64) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.versions[pair] != version {
+		return false
+	}
+	rc.entries[pair] = cachedReserves{reserves: reserves, fetchedAt: time.Now()}
+
+	if rc.ttlOnly || rc.watched[pair] || len(rc.watched) >= maxWatchedPairs {
+		return false
+	}
+	rc.watched[pair] = true
+	return true
+}
+
+func (rc *reservesCache) evict(pair common.Address) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	delete(rc.entries, pair)
+	rc.versions[pair]++
+}
+
+// unwatch drops pair's subscription. Its entry is evicted too, since Sync
+// events may have been missed.
+func (rc *reservesCache) unwatch(pair common.Address) {
+	rc.mu.Lock()
+	delete(rc.watched, pair)
+	rc.mu.Unlock()
+
+	rc.evict(pair)
+}
+
+func (rc *reservesCache) disableWatching() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.ttlOnly = true
+	clear(rc.watched)
+}
+
+// getCachedReserves serves latest-block reserves from the cache, fetching
+// and caching them on a miss.
+func (ec *EthereumClient) getCachedReserves(pairAddr common.Address, fetch func() (*PoolReserves, error)) (*PoolReserves, error) {
+	rc := ec.reservesCache
+
+	reserves, version := rc.get(pairAddr)
+	if reserves != nil {
+		return copyReserves(reserves), nil
+	}
+
+	reserves, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	if rc.put(pairAddr, reserves, version) {
+		go ec.watchSync(rc, pairAddr)
+	}
+	return copyReserves(reserves), nil
+}
+
+// watchSync evicts pair from rc each time it emits Sync, until rc is closed
+// or the subscription fails.
+func (ec *EthereumClient) watchSync(rc *reservesCache, pair common.Address) {
+	logs := make(chan types.Log)
+	sub, err := ec.conn().SubscribeFilterLogs(rc.ctx, ethereum.FilterQuery{
+		Addresses: []common.Address{pair},
+		// Sync is emitted whenever the reserves change
+		Topics: [][]common.Hash{{ec.abi.Events["Sync"].ID}},
+	}, logs)
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+			slog.Info("Node doesn't support log subscriptions; cached reserves expire by TTL only")
+			rc.disableWatching()
+			return
+		}
+		slog.Warn("failed to watch pair for Sync events", "pair", pair.Hex(), "error", err)
+		rc.unwatch(pair)
+		return
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-rc.ctx.Done():
+			return
+		case err := <-sub.Err():
+			slog.Warn("Sync subscription failed", "pair", pair.Hex(), "error", err)
+			rc.unwatch(pair)
+			return
+		case <-logs:
+			rc.evict(pair)
+		}
+	}
+}
+
+// copyReserves keeps callers from mutating a cached value.
+func copyReserves(r *PoolReserves) *PoolReserves {
+	return &PoolReserves{
+		Reserve0:           new(big.Int).Set(r.Reserve0),
+		Reserve1:           new(big.Int).Set(r.Reserve1),
+		BlockTimestampLast: r.BlockTimestampLast,
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+type ReservesResponse struct {
+	Token0             string `json:"token0"`
+	Token1             string `json:"token1"`
+	Reserve0           string `json:"reserve0"`
+	Reserve1           string `json:"reserve1"`
+	BlockTimestampLast uint32 `json:"block_timestamp_last"`
+}
+
+// reservesHandler returns a pair's raw state for clients that do their own
+// math. It accepts the same chain_id and block parameters as /estimate.
+func (se *SwapEstimator) reservesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	poolStr := query.Get("pool")
+	if poolStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameter: pool")
+		return
+	}
+
+	poolAddr, err := parseAddress("pool", poolStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	blockNumber, err := parseBlockParams(query.Get("block"), query.Get("block_tag"))
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	se, err = se.forChain(query.Get("chain_id"))
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if !errors.Is(err, ErrChainNotConfigured) {
+			slog.ErrorContext(r.Context(), "reserves lookup failed", "pool", poolStr, "error", err)
+			status, message = http.StatusInternalServerError, "Failed to connect to chain"
+		}
+		writeError(w, status, errorCode(status, err), message)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	state, err := se.GetPoolState(ctx, poolAddr, blockNumber)
+	if err != nil {
+		slog.WarnContext(ctx, "reserves lookup failed", "pool", poolStr, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	response := ReservesResponse{
+		Token0:             state.Token0.Hex(),
+		Token1:             state.Token1.Hex(),
+		Reserve0:           state.Reserve0.String(),
+		Reserve1:           state.Reserve1.String(),
+		BlockTimestampLast: state.BlockTimestampLast,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// maxCachedResponses bounds the response cache; once it's full, new
+// responses aren't cached until entries expire.
+const maxCachedResponses = 10000
+
+var responseCacheHitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "estimator_response_cache_hits_total",
+	Help: "Number of estimate requests answered from the response cache.",
+})
+
+type cachedResponse struct {
+	body      []byte
+	expiresAt time.Time
+}
+
+// responseCache replays the body of a successful /estimate response to
+// identical requests for up to ttl, so clients retrying the same quote don't
+// each cost a round of node calls. Requests are keyed by every parameter,
+// since any of them can change the response.
+type responseCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[EstimateRequest]cachedResponse
+}
+
+// SetResponseCacheTTL caches successful estimate responses for ttl. A ttl of
+// 0 disables the cache.
+func (se *SwapEstimator) SetResponseCacheTTL(ttl time.Duration) {
+	se.responseCache = nil
+	if ttl > 0 {
+		se.responseCache = &responseCache{ttl: ttl, entries: make(map[EstimateRequest]cachedResponse)}
+	}
+}
+
+// responseCacheKey normalizes the addresses in req so that requests which
+// only differ in address case, or in how they spell ETH, share an entry.
+// Addresses that parseAddress would reject are left alone, so a bad checksum
+// can never be answered from the entry of the valid address.
+func responseCacheKey(req EstimateRequest) EstimateRequest {
+	for _, field := range []*string{&req.Pool, &req.Src, &req.Dst, &req.Owner} {
+		if strings.EqualFold(*field, "ETH") {
+			*field = "ETH"
+		} else if addr, err := parseAddress("", *field); err == nil {
+			*field = addr.Hex()
+		}
+	}
+	return req
+}
+
+// get returns the cached body for req, if fresh. It is safe to call on a nil
+// cache.
+func (rc *responseCache) get(req EstimateRequest) ([]byte, bool) {
+	if rc == nil {
+		return nil, false
+	}
+	req = responseCacheKey(req)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	entry, ok := rc.entries[req]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(rc.entries, req)
+		return nil, false
+	}
+	responseCacheHitsTotal.Inc()
+	return entry.body, true
+}
+
+// clear drops every entry. It is safe to call on a nil cache.
+func (rc *responseCache) clear() {
+	if rc == nil {
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	clear(rc.entries)
+}
+
+func (rc *responseCache) put(req EstimateRequest, body []byte) {
+	if rc == nil {
+		return
+	}
+	req = responseCacheKey(req)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := time.Now()
+	if len(rc.entries) >= maxCachedResponses {
+		for key, entry := range rc.entries {
+			if now.After(entry.expiresAt) {
+				delete(rc.entries, key)
+			}
+		}
+		if len(rc.entries) >= maxCachedResponses {
+			return
+		}
+	}
+	rc.entries[req] = cachedResponse{body: body, expiresAt: now.Add(rc.ttl)}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseCacheKeyNormalizesAddresses(t *testing.T) {
+	checksummed := EstimateRequest{
+		Pool:      testPool.Hex(),
+		Src:       testToken0.Hex(),
+		Dst:       testToken1.Hex(),
+		SrcAmount: "1000000000000000000",
+	}
+	lower := EstimateRequest{
+		Pool:      strings.ToLower(testPool.Hex()),
+		Src:       strings.ToLower(testToken0.Hex()),
+		Dst:       strings.ToLower(testToken1.Hex()),
+		SrcAmount: "1000000000000000000",
+	}
+	upper := EstimateRequest{
+		Pool:      "0x" + strings.ToUpper(testPool.Hex()[2:]),
+		Src:       "0x" + strings.ToUpper(testToken0.Hex()[2:]),
+		Dst:       "0x" + strings.ToUpper(testToken1.Hex()[2:]),
+		SrcAmount: "1000000000000000000",
+	}
+
+	rc := &responseCache{ttl: time.Minute, entries: make(map[EstimateRequest]cachedResponse)}
+	rc.put(checksummed, []byte("cached"))
+	rc.put(lower, []byte("cached"))
+	rc.put(upper, []byte("cached"))
+
+	if len(rc.entries) != 1 {
+		t.Fatalf("cache holds %d entries, want 1", len(rc.entries))
+	}
+	for _, req := range []EstimateRequest{checksummed, lower, upper} {
+		if body, ok := rc.get(req); !ok || string(body) != "cached" {
+			t.Errorf("get(%+v) = %q, %v, want a hit", req, body, ok)
+		}
+	}
+}
+
+func TestResponseCacheKeyNormalizesETH(t *testing.T) {
+	rc := &responseCache{ttl: time.Minute, entries: make(map[EstimateRequest]cachedResponse)}
+	rc.put(EstimateRequest{Pool: testPool.Hex(), Src: "eth", Dst: testToken1.Hex(), SrcAmount: "1"}, []byte("cached"))
+
+	if _, ok := rc.get(EstimateRequest{Pool: strings.ToLower(testPool.Hex()), Src: "ETH", Dst: testToken1.Hex(), SrcAmount: "1"}); !ok {
+		t.Error("ETH and eth don't share an entry")
+	}
+}
+
+// TestResponseCacheKeyKeepsBadChecksum makes sure an address that fails its
+// checksum is never answered from the valid address's entry.
+func TestResponseCacheKeyKeepsBadChecksum(t *testing.T) {
+	// Flip the case of the first letter after the 0x prefix
+	good := testPool.Hex()
+	i := strings.IndexAny(good[2:], "abcdefABCDEF") + 2
+	flipped := strings.ToUpper(good[i : i+1])
+	if flipped == good[i:i+1] {
+		flipped = strings.ToLower(flipped)
+	}
+	bad := good[:i] + flipped + good[i+1:]
+
+	rc := &responseCache{ttl: time.Minute, entries: make(map[EstimateRequest]cachedResponse)}
+	rc.put(EstimateRequest{Pool: good, Src: testToken0.Hex(), Dst: testToken1.Hex(), SrcAmount: "1"}, []byte("cached"))
+
+	if _, ok := rc.get(EstimateRequest{Pool: bad, Src: testToken0.Hex(), Dst: testToken1.Hex(), SrcAmount: "1"}); ok {
+		t.Errorf("pool %s with a bad checksum hit the entry for %s", bad, good)
+	}
+}
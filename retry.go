@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	defaultRPCMaxRetries = 3
+	retryBaseDelay       = 100 * time.Millisecond
+	retryMaxDelay        = 2 * time.Second
+)
+
+// withRetry runs call until it succeeds, returns a non-transient error, runs
+// out of retries, or ctx is done.
+func withRetry[T any](ctx context.Context, maxRetries int, call func() (T, error)) (T, error) {
+	var (
+		result T
+		err    error
+	)
+
+	for attempt := 0; ; attempt++ {
+		result, err = call()
+		if err == nil || attempt >= maxRetries || !isTransientError(err) {
+			return result, err
+		}
+		slog.DebugContext(ctx, "retrying node call", "attempt", attempt+1, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(backoffDelay(attempt)):
+		}
+	}
+}
+
+// backoffDelay doubles the delay on every attempt and applies full jitter.
+func backoffDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << attempt
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return time.Duration(rand.Int64N(int64(delay)) + 1)
+}
+
+// isTransientError reports whether err is a network failure or rate limit
+// that is worth retrying. Reverts and other contract-level errors are not.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		return false
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		// -32005 is the conventional "limit exceeded" code used by most providers
+		if rpcErr.ErrorCode() == -32005 {
+			return true
+		}
+	}
+
+	// ErrClientQuit means the connection was replaced mid-call; the retry
+	// runs on the new one
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, rpc.ErrClientQuit) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests")
+}
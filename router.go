@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Uniswap V2 Router02 on mainnet
+var defaultRouterAddress = common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+
+const (
+	engineLocal  = "local"
+	engineRouter = "router"
+)
+
+// GetAmountsOut calls router's getAmountsOut, which returns the amounts at
+// each step of path, starting with amountIn. The router looks the pairs up
+// through its own factory and reverts when one doesn't exist.
+func (ec *EthereumClient) GetAmountsOut(ctx context.Context, router common.Address, amountIn *big.Int, path []common.Address, blockNumber *big.Int) ([]*big.Int, error) {
+	data, err := ec.routerABI.Pack("getAmountsOut", amountIn, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack getAmountsOut call: %w", err)
+	}
+
+	result, err := ec.callContract(ctx, "getAmountsOut", router, data, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call getAmountsOut: %w", err)
+	}
+
+	unpacked, err := ec.routerABI.Unpack("getAmountsOut", result)
+	if err != nil || len(unpacked) != 1 {
+		return nil, fmt.Errorf("%w: failed to unpack getAmountsOut result", errMalformedResult)
+	}
+
+	amounts, ok := unpacked[0].([]*big.Int)
+	if !ok || len(amounts) != len(path) {
+		return nil, fmt.Errorf("%w: getAmountsOut returned %d amounts for a path of %d tokens", errMalformedResult, len(amounts), len(path))
+	}
+
+	return amounts, nil
+}
+
+func (se *SwapEstimator) SetRouter(routerAddr common.Address) {
+	se.router = routerAddr
+}
+
+// EstimateSwapViaRouter returns the router's getAmountsOut quote for a single
+// hop. It prices whichever pair the router's factory holds for the tokens,
+// which is only the requested pool if both come from the same factory.
+func (se *SwapEstimator) EstimateSwapViaRouter(ctx context.Context, srcToken, dstToken common.Address, srcAmount, blockNumber *big.Int) (*big.Int, error) {
+	amounts, err := se.ethClient.GetAmountsOut(ctx, se.router, srcAmount, []common.Address{srcToken, dstToken}, blockNumber)
+	if err != nil {
+		if isTimeout(err) {
+			return nil, fmt.Errorf("failed to quote swap via router: %w", err)
+		}
+		if errors.Is(err, ErrStateUnavailable) {
+			return nil, err
+		}
+		if isContractFailure(err) {
+			return nil, fmt.Errorf("%w: router %s has no pool for %s/%s", ErrPoolNotFound, se.router.Hex(), srcToken.Hex(), dstToken.Hex())
+		}
+		return nil, fmt.Errorf("%w: failed to quote swap via router: %w", ErrRPCFailure, err)
+	}
+
+	return amounts[len(amounts)-1], nil
+}
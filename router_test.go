@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// routerGetAmountOut is UniswapV2Library.getAmountOut as the router runs it,
+// written out separately from calculateSwapAmount so the two can be compared.
+func routerGetAmountOut(amountIn, reserveIn, reserveOut *big.Int) *big.Int {
+	amountInWithFee := new(big.Int).Mul(amountIn, big.NewInt(997))
+	numerator := new(big.Int).Mul(amountInWithFee, reserveOut)
+	denominator := new(big.Int).Add(new(big.Int).Mul(reserveIn, big.NewInt(1000)), amountInWithFee)
+	return numerator.Div(numerator, denominator)
+}
+
+func TestEstimateSwapViaRouterMatchesLocal(t *testing.T) {
+	node := newMockNode(t)
+	ec := node.client()
+	reserves := testReserves(t)
+	mockPair(t, node, ec, testPool, testToken0, testToken1, reserves.Reserve0, reserves.Reserve1, reserves.BlockTimestampLast)
+
+	se := NewSwapEstimator(ec)
+	router := se.router
+
+	for _, tt := range []struct {
+		name                  string
+		src, dst              common.Address
+		reserveIn, reserveOut *big.Int
+	}{
+		{"token0 to token1", testToken0, testToken1, reserves.Reserve0, reserves.Reserve1},
+		{"token1 to token0", testToken1, testToken0, reserves.Reserve1, reserves.Reserve0},
+	} {
+		for _, in := range []string{"1000", "999999999999", "1000000000000000000", "33333333333333333333", "100000000000000000000", "1000000000000000000000"} {
+			amountIn := bigInt(t, in)
+
+			amounts := []*big.Int{amountIn, routerGetAmountOut(amountIn, tt.reserveIn, tt.reserveOut)}
+			result, err := ec.routerABI.Methods["getAmountsOut"].Outputs.Pack(amounts)
+			if err != nil {
+				t.Fatalf("pack getAmountsOut: %v", err)
+			}
+			node.reply(router, ec.routerABI.Methods["getAmountsOut"], mockReply{result: result})
+
+			viaRouter, err := se.EstimateSwapViaRouter(context.Background(), tt.src, tt.dst, amountIn, nil)
+			if err != nil {
+				t.Fatalf("%s, amountIn %s: EstimateSwapViaRouter: %v", tt.name, in, err)
+			}
+			local, err := se.EstimateSwap(context.Background(), testPool, tt.src, tt.dst, amountIn)
+			if err != nil {
+				t.Fatalf("%s, amountIn %s: EstimateSwap: %v", tt.name, in, err)
+			}
+
+			if viaRouter.Cmp(local.AmountOut) != 0 {
+				t.Errorf("%s, amountIn %s: router quoted %s, local math %s", tt.name, in, viaRouter, local.AmountOut)
+			}
+		}
+	}
+}
+
+func TestEstimateSwapViaRouterNoPool(t *testing.T) {
+	node := newMockNode(t)
+	ec := node.client()
+	se := NewSwapEstimator(ec)
+	node.reply(se.router, ec.routerABI.Methods["getAmountsOut"], mockReply{revert: true})
+
+	_, err := se.EstimateSwapViaRouter(context.Background(), testToken0, testToken1, bigInt(t, "1000"), nil)
+	if !errors.Is(err, ErrPoolNotFound) {
+		t.Fatalf("err = %v, want %v", err, ErrPoolNotFound)
+	}
+}
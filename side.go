@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	sideBuy  = "buy"
+	sideSell = "sell"
+)
+
+// TradeResponse is the same shape for both sides, so base_amount is always
+// the base token traded and quote_amount the quote token paid or received.
+type TradeResponse struct {
+	Side string `json:"side"`
+	// BaseToken is the pool's token0 and QuoteToken its token1
+	BaseToken  string `json:"base_token"`
+	QuoteToken string `json:"quote_token"`
+	BaseAmount string `json:"base_amount"`
+	// QuoteAmount is paid for a buy and received for a sell
+	QuoteAmount string `json:"quote_amount"`
+	Src         string `json:"src"`
+	Dst         string `json:"dst"`
+	SrcAmount   string `json:"src_amount"`
+	DstAmount   string `json:"dst_amount"`
+}
+
+// EstimateTrade quotes a trade of baseAmount of the pool's base token,
+// token0, against its quote token, token1. A sell spends exactly baseAmount;
+// a buy receives exactly baseAmount and the quote amount is the input needed,
+// rounded up like EstimateSwapForExactOutput.
+func (se *SwapEstimator) EstimateTrade(ctx context.Context, poolAddr common.Address, side string, baseAmount *big.Int, blockNumber *big.Int) (*TradeResponse, error) {
+	state, err := se.GetPoolState(ctx, poolAddr, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Reserve0.Sign() == 0 || state.Reserve1.Sign() == 0 {
+		return nil, fmt.Errorf("%w: %s has reserves %s/%s", ErrNoLiquidity, poolAddr.Hex(), state.Reserve0, state.Reserve1)
+	}
+
+	response := &TradeResponse{
+		Side:       side,
+		BaseToken:  state.Token0.Hex(),
+		QuoteToken: state.Token1.Hex(),
+		BaseAmount: baseAmount.String(),
+	}
+
+	var quoteAmount *big.Int
+	switch side {
+	case sideSell:
+		quoteAmount = calculateSwapAmount(baseAmount, state.Reserve0, state.Reserve1, se.fee)
+		if err := checkAmountOut(quoteAmount, state.Reserve1); err != nil {
+			return nil, fmt.Errorf("pool %s: %w", poolAddr.Hex(), err)
+		}
+		response.Src, response.Dst = response.BaseToken, response.QuoteToken
+		response.SrcAmount, response.DstAmount = baseAmount.String(), quoteAmount.String()
+	case sideBuy:
+		if baseAmount.Cmp(state.Reserve0) >= 0 {
+			return nil, fmt.Errorf("%w: requested output %s exceeds available reserve %s", ErrInsufficientLiquidity, baseAmount, state.Reserve0)
+		}
+		var err error
+		quoteAmount, err = calculateSwapAmountIn(baseAmount, state.Reserve1, state.Reserve0, se.fee)
+		if err != nil {
+			return nil, err
+		}
+		response.Src, response.Dst = response.QuoteToken, response.BaseToken
+		response.SrcAmount, response.DstAmount = quoteAmount.String(), baseAmount.String()
+	default:
+		return nil, fmt.Errorf("unknown side %q", side)
+	}
+
+	response.QuoteAmount = quoteAmount.String()
+	return response, nil
+}
+
+func (se *SwapEstimator) tradeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	poolStr := query.Get("pool")
+	side := query.Get("side")
+	amountStr := query.Get("amount")
+
+	if poolStr == "" || side == "" || amountStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameters: pool, side, amount")
+		return
+	}
+
+	if side != sideBuy && side != sideSell {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "Invalid side: must be buy or sell")
+		return
+	}
+
+	poolAddr, err := parseAddress("pool", poolStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// A sell's amount is the swap input, so it is bounded like src_amount
+	var amount *big.Int
+	if side == sideSell {
+		amount, err = se.parseInputAmount("amount", amountStr)
+	} else {
+		amount, err = parseAmount("amount", amountStr)
+	}
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	blockNumber, err := parseBlockParams(query.Get("block"), query.Get("block_tag"))
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	se, err = se.forChain(query.Get("chain_id"))
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if !errors.Is(err, ErrChainNotConfigured) {
+			slog.ErrorContext(r.Context(), "trade estimate failed", "pool", poolStr, "error", err)
+			status, message = http.StatusInternalServerError, "Failed to connect to chain"
+		}
+		writeError(w, status, errorCode(status, err), message)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	response, err := se.EstimateTrade(ctx, poolAddr, side, amount, blockNumber)
+	if err != nil {
+		slog.WarnContext(ctx, "trade estimate failed", "pool", poolStr, "side", side, "amount", amountStr, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+type SideQuote struct {
+	Src         string `json:"src"`
+	Dst         string `json:"dst"`
+	SrcAmount   string `json:"src_amount"`
+	DstAmount   string `json:"dst_amount"`
+	PriceImpact string `json:"price_impact"`
+}
+
+// BothSidesResponse quotes the same size of token in each direction.
+type BothSidesResponse struct {
+	Token string `json:"token"`
+	// Other is the pool's other token, which both sides are priced in
+	Other string `json:"other"`
+	// Sell spends exactly amount of token; Buy receives exactly amount of it
+	Sell SideQuote  `json:"sell"`
+	Buy  *SideQuote `json:"buy,omitempty"`
+	// BuyError is set instead of Buy when amount can't be bought because it
+	// isn't below the pool's reserve of token
+	BuyError *ErrorResponse `json:"buy_error,omitempty"`
+}
+
+// EstimateBothSides quotes selling and buying amount of token against the
+// pool's other token from a single read of the reserves. The buy side's
+// input is rounded up like EstimateSwapForExactOutput. Selling works for any
+// amount, so when the pool holds too little of token to buy amount the sell
+// side is still returned, with the buy side's error in BuyError.
+func (se *SwapEstimator) EstimateBothSides(ctx context.Context, poolAddr, token common.Address, amount *big.Int, blockNumber *big.Int) (*BothSidesResponse, error) {
+	state, err := se.GetPoolState(ctx, poolAddr, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Reserve0.Sign() == 0 || state.Reserve1.Sign() == 0 {
+		return nil, fmt.Errorf("%w: %s has reserves %s/%s", ErrNoLiquidity, poolAddr.Hex(), state.Reserve0, state.Reserve1)
+	}
+
+	var other common.Address
+	var reserveToken, reserveOther *big.Int
+	switch token {
+	case state.Token0:
+		other, reserveToken, reserveOther = state.Token1, state.Reserve0, state.Reserve1
+	case state.Token1:
+		other, reserveToken, reserveOther = state.Token0, state.Reserve1, state.Reserve0
+	default:
+		return nil, fmt.Errorf("%w: token %s isn't in pool tokens %s/%s", ErrTokenMismatch, token.Hex(), state.Token0.Hex(), state.Token1.Hex())
+	}
+
+	sellOut := calculateSwapAmount(amount, reserveToken, reserveOther, se.fee)
+	if err := checkAmountOut(sellOut, reserveOther); err != nil {
+		return nil, fmt.Errorf("pool %s: %w", poolAddr.Hex(), err)
+	}
+
+	response := &BothSidesResponse{
+		Token: token.Hex(),
+		Other: other.Hex(),
+		Sell: SideQuote{
+			Src:         token.Hex(),
+			Dst:         other.Hex(),
+			SrcAmount:   amount.String(),
+			DstAmount:   sellOut.String(),
+			PriceImpact: calculatePriceImpact(amount, sellOut, reserveToken, reserveOther).FloatString(4),
+		},
+	}
+
+	if amount.Cmp(reserveToken) >= 0 {
+		err := fmt.Errorf("%w: requested output %s exceeds available reserve %s", ErrInsufficientLiquidity, amount, reserveToken)
+		response.BuyError = &ErrorResponse{Error: err.Error(), Code: errorCode(estimateErrorStatus(err), err)}
+		return response, nil
+	}
+	buyIn, err := calculateSwapAmountIn(amount, reserveOther, reserveToken, se.fee)
+	if err != nil {
+		return nil, err
+	}
+
+	response.Buy = &SideQuote{
+		Src:         other.Hex(),
+		Dst:         token.Hex(),
+		SrcAmount:   buyIn.String(),
+		DstAmount:   amount.String(),
+		PriceImpact: calculatePriceImpact(buyIn, amount, reserveOther, reserveToken).FloatString(4),
+	}
+	return response, nil
+}
+
+func (se *SwapEstimator) bothSidesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	poolStr := query.Get("pool")
+	tokenStr := query.Get("token")
+	amountStr := query.Get("amount")
+
+	if poolStr == "" || tokenStr == "" || amountStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameters: pool, token, amount")
+		return
+	}
+
+	poolAddr, err := parseAddress("pool", poolStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tokenAddr, err := parseAddress("token", tokenStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// amount is the sell side's swap input, so it is bounded like src_amount
+	amount, err := se.parseInputAmount("amount", amountStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	blockNumber, err := parseBlockParams(query.Get("block"), query.Get("block_tag"))
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	se, err = se.forChain(query.Get("chain_id"))
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if !errors.Is(err, ErrChainNotConfigured) {
+			slog.ErrorContext(r.Context(), "two-sided estimate failed", "pool", poolStr, "error", err)
+			status, message = http.StatusInternalServerError, "Failed to connect to chain"
+		}
+		writeError(w, status, errorCode(status, err), message)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	response, err := se.EstimateBothSides(ctx, poolAddr, tokenAddr, amount, blockNumber)
+	if err != nil {
+		slog.WarnContext(ctx, "two-sided estimate failed", "pool", poolStr, "token", tokenStr, "amount", amountStr, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
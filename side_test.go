@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestEstimateTrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		side       string
+		baseAmount string
+		// src and dst are token0 (base) and token1 (quote) in trade order
+		src, dst                   common.Address
+		srcAmount, dstAmount, want string
+	}{
+		{"sell spends the base amount", sideSell, "1000000000000000000", testToken0, testToken1, "1000000000000000000", "1974316068794122597", "1974316068794122597"},
+		{"buy receives the base amount", sideBuy, "1000000000000000000", testToken1, testToken0, "2026280862790391377", "1000000000000000000", "2026280862790391377"},
+		{"large sell", sideSell, "50000000000000000000", testToken0, testToken1, "50000000000000000000", "66533199866533199866", "66533199866533199866"},
+		{"large buy", sideBuy, "50000000000000000000", testToken1, testToken0, "200601805416248746239", "50000000000000000000", "200601805416248746239"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := newFakeChain(map[common.Address]fakePair{
+				testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+			})
+			se := NewSwapEstimator(chain)
+
+			got, err := se.EstimateTrade(context.Background(), testPool, tt.side, bigInt(t, tt.baseAmount), nil)
+			if err != nil {
+				t.Fatalf("EstimateTrade: %v", err)
+			}
+
+			if got.Side != tt.side || got.BaseToken != testToken0.Hex() || got.QuoteToken != testToken1.Hex() {
+				t.Errorf("side %s base %s quote %s, want %s with base token0 and quote token1", got.Side, got.BaseToken, got.QuoteToken, tt.side)
+			}
+			if got.BaseAmount != tt.baseAmount {
+				t.Errorf("BaseAmount = %s, want %s", got.BaseAmount, tt.baseAmount)
+			}
+			if got.QuoteAmount != tt.want {
+				t.Errorf("QuoteAmount = %s, want %s", got.QuoteAmount, tt.want)
+			}
+			if got.Src != tt.src.Hex() || got.Dst != tt.dst.Hex() {
+				t.Errorf("route %s -> %s, want %s -> %s", got.Src, got.Dst, tt.src.Hex(), tt.dst.Hex())
+			}
+			if got.SrcAmount != tt.srcAmount || got.DstAmount != tt.dstAmount {
+				t.Errorf("amounts %s -> %s, want %s -> %s", got.SrcAmount, got.DstAmount, tt.srcAmount, tt.dstAmount)
+			}
+		})
+	}
+}
+
+// TestEstimateTradeBuyRoundTrips spends each buy's quote amount as a sell
+// of the quote token and checks it buys at least the requested base amount.
+func TestEstimateTradeBuyRoundTrips(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+	})
+	se := NewSwapEstimator(chain)
+
+	for _, base := range []string{"1", "999", "1000000000000000000", "12345678901234567890", "99000000000000000000"} {
+		buy, err := se.EstimateTrade(context.Background(), testPool, sideBuy, bigInt(t, base), nil)
+		if err != nil {
+			t.Fatalf("buy %s: %v", base, err)
+		}
+
+		est, err := se.EstimateSwap(context.Background(), testPool, testToken1, testToken0, bigInt(t, buy.QuoteAmount))
+		if err != nil {
+			t.Fatalf("swap %s quote back: %v", buy.QuoteAmount, err)
+		}
+		if est.AmountOut.Cmp(bigInt(t, base)) < 0 {
+			t.Errorf("buying %s costs %s, but that only swaps for %s", base, buy.QuoteAmount, est.AmountOut)
+		}
+	}
+}
+
+func TestEstimateTradeBuyExceedsReserve(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+	})
+	se := NewSwapEstimator(chain)
+
+	_, err := se.EstimateTrade(context.Background(), testPool, sideBuy, bigInt(t, "100000000000000000000"), nil)
+	if !errors.Is(err, ErrInsufficientLiquidity) {
+		t.Fatalf("err = %v, want %v", err, ErrInsufficientLiquidity)
+	}
+}
+
+// TestTradeHandlerBoundsSellAmount checks that a sell's amount, the swap
+// input, is capped by MAX_SRC_AMOUNT while a buy's amount, the output, isn't.
+func TestTradeHandlerBoundsSellAmount(t *testing.T) {
+	tests := []struct {
+		side       string
+		wantStatus int
+	}{
+		{sideSell, http.StatusBadRequest},
+		{sideBuy, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.side, func(t *testing.T) {
+			chain := newFakeChain(map[common.Address]fakePair{
+				testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+			})
+			se := NewSwapEstimator(chain)
+			se.SetMaxSrcAmount(bigInt(t, "1000000000000000000"))
+
+			req := httptest.NewRequest(http.MethodGet, "/trade?pool="+testPool.Hex()+"&side="+tt.side+"&amount=2000000000000000000", nil)
+			rec := httptest.NewRecorder()
+			se.tradeHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestEstimateBothSides(t *testing.T) {
+	tests := []struct {
+		name           string
+		token, other   common.Address
+		sellOut, buyIn string
+	}{
+		{"token0", testToken0, testToken1, "1974316068794122597", "2026280862790391377"},
+		{"token1", testToken1, testToken0, "496027303890107812", "504024636724243082"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := newFakeChain(map[common.Address]fakePair{
+				testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+			})
+			se := NewSwapEstimator(chain)
+			amount := "1000000000000000000"
+
+			got, err := se.EstimateBothSides(context.Background(), testPool, tt.token, bigInt(t, amount), nil)
+			if err != nil {
+				t.Fatalf("EstimateBothSides: %v", err)
+			}
+
+			// Both sides come from the one read of the reserves
+			if reads := chain.reserveReads.Load(); reads != 1 {
+				t.Errorf("read reserves %d times, want 1", reads)
+			}
+			if got.Token != tt.token.Hex() || got.Other != tt.other.Hex() {
+				t.Errorf("token %s other %s, want %s and %s", got.Token, got.Other, tt.token.Hex(), tt.other.Hex())
+			}
+
+			sell := SideQuote{Src: tt.token.Hex(), Dst: tt.other.Hex(), SrcAmount: amount, DstAmount: tt.sellOut}
+			if got.Sell.Src != sell.Src || got.Sell.Dst != sell.Dst || got.Sell.SrcAmount != sell.SrcAmount || got.Sell.DstAmount != sell.DstAmount {
+				t.Errorf("sell = %+v, want %+v", got.Sell, sell)
+			}
+			buy := SideQuote{Src: tt.other.Hex(), Dst: tt.token.Hex(), SrcAmount: tt.buyIn, DstAmount: amount}
+			if got.Buy == nil {
+				t.Fatalf("buy is missing, buy_error %+v", got.BuyError)
+			}
+			if got.Buy.Src != buy.Src || got.Buy.Dst != buy.Dst || got.Buy.SrcAmount != buy.SrcAmount || got.Buy.DstAmount != buy.DstAmount {
+				t.Errorf("buy = %+v, want %+v", got.Buy, buy)
+			}
+
+			// The sell side matches a plain estimate of the same swap
+			est, err := se.EstimateSwap(context.Background(), testPool, tt.token, tt.other, bigInt(t, amount))
+			if err != nil {
+				t.Fatalf("EstimateSwap: %v", err)
+			}
+			if got.Sell.DstAmount != est.AmountOut.String() || got.Sell.PriceImpact != est.PriceImpact.FloatString(4) {
+				t.Errorf("sell %s at %s%% impact, EstimateSwap %s at %s%%", got.Sell.DstAmount, got.Sell.PriceImpact, est.AmountOut, est.PriceImpact.FloatString(4))
+			}
+		})
+	}
+}
+
+func TestEstimateBothSidesRejectsOtherToken(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+	})
+	se := NewSwapEstimator(chain)
+
+	_, err := se.EstimateBothSides(context.Background(), testPool, testOther, bigInt(t, "1000"), nil)
+	if !errors.Is(err, ErrTokenMismatch) {
+		t.Fatalf("err = %v, want %v", err, ErrTokenMismatch)
+	}
+}
+
+// TestEstimateBothSidesBuyExceedsReserve sells the pool's whole reserve of
+// token0, which can't be bought back, and checks the sell side still quotes.
+func TestEstimateBothSidesBuyExceedsReserve(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+	})
+	se := NewSwapEstimator(chain)
+
+	got, err := se.EstimateBothSides(context.Background(), testPool, testToken0, bigInt(t, "100000000000000000000"), nil)
+	if err != nil {
+		t.Fatalf("EstimateBothSides: %v", err)
+	}
+
+	if got.Sell.DstAmount != "99849774661992989484" {
+		t.Errorf("sell dst_amount = %s, want 99849774661992989484", got.Sell.DstAmount)
+	}
+	if got.Buy != nil {
+		t.Errorf("buy = %+v, want none", got.Buy)
+	}
+	if got.BuyError == nil || got.BuyError.Code != CodeInsufficientLiquidity {
+		t.Errorf("buy_error = %+v, want code %s", got.BuyError, CodeInsufficientLiquidity)
+	}
+}
+
+func TestBothSidesHandlerBoundsAmount(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+	})
+	se := NewSwapEstimator(chain)
+	se.SetMaxSrcAmount(bigInt(t, "1000000000000000000"))
+
+	req := httptest.NewRequest(http.MethodGet, "/estimate_both?pool="+testPool.Hex()+"&token="+testToken0.Hex()+"&amount=2000000000000000000", nil)
+	rec := httptest.NewRecorder()
+	se.bothSidesHandler(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type SplitLeg struct {
+	Pool        string `json:"pool"`
+	SrcAmount   string `json:"src_amount"`
+	DstAmount   string `json:"dst_amount"`
+	PriceImpact string `json:"price_impact"`
+}
+
+type EstimateSplitResponse struct {
+	// DstAmount is the combined output of both legs
+	DstAmount   string     `json:"dst_amount"`
+	Splits      []SplitLeg `json:"splits"`
+	WrapsETH    bool       `json:"wraps_eth,omitempty"`
+	UnwrapsWETH bool       `json:"unwraps_weth,omitempty"`
+}
+
+// optimalSplit returns how much of amountIn to route through the first pool
+// so that both pools end at the same marginal price, which maximizes the
+// combined output. A pool's marginal output for input x is
+// γ·rIn·rOut/(rIn+γx)², so equalizing the two gives
+//
+//	x = (s1·(rIn2 + γA) − s2·rIn1) / (γ·(s1 + s2))
+//
+// where s = √(rIn·rOut) and A is amountIn. The result is clamped to
+// [0, amountIn], which is where one pool is too shallow to take any of it.
+func optimalSplit(amountIn, reserveIn1, reserveOut1, reserveIn2, reserveOut2 *big.Int, fee SwapFee) *big.Int {
+	s1 := new(big.Int).Sqrt(new(big.Int).Mul(reserveIn1, reserveOut1))
+	s2 := new(big.Int).Sqrt(new(big.Int).Mul(reserveIn2, reserveOut2))
+
+	// Scaled by the fee denominator so γ stays an integer ratio
+	numerator := new(big.Int).Mul(reserveIn2, big.NewInt(fee.Denominator))
+	numerator.Add(numerator, new(big.Int).Mul(amountIn, big.NewInt(fee.Numerator)))
+	numerator.Mul(numerator, s1)
+	numerator.Sub(numerator, new(big.Int).Mul(s2, new(big.Int).Mul(reserveIn1, big.NewInt(fee.Denominator))))
+
+	denominator := new(big.Int).Add(s1, s2)
+	denominator.Mul(denominator, big.NewInt(fee.Numerator))
+
+	if numerator.Sign() <= 0 || denominator.Sign() == 0 {
+		return new(big.Int)
+	}
+
+	amountIn1 := numerator.Quo(numerator, denominator)
+	if amountIn1.Cmp(amountIn) > 0 {
+		return new(big.Int).Set(amountIn)
+	}
+	return amountIn1
+}
+
+// EstimateSplit splits srcAmount between two pools for the same pair so the
+// combined output is as large as possible, and quotes each leg.
+func (se *SwapEstimator) EstimateSplit(ctx context.Context, pool1, pool2, srcToken, dstToken common.Address, srcAmount *big.Int) (*EstimateSplitResponse, error) {
+	pools := []common.Address{pool1, pool2}
+	reserves := make([]*directionalReserves, len(pools))
+	for i, pool := range pools {
+		r, err := se.getDirectionalReserves(ctx, pool, srcToken, dstToken, nil)
+		if err != nil {
+			return nil, fmt.Errorf("pool %s: %w", pool.Hex(), err)
+		}
+		reserves[i] = r
+	}
+
+	amountIn1 := optimalSplit(srcAmount, reserves[0].ReserveIn, reserves[0].ReserveOut, reserves[1].ReserveIn, reserves[1].ReserveOut, se.fee)
+	amountsIn := []*big.Int{amountIn1, new(big.Int).Sub(srcAmount, amountIn1)}
+
+	response := &EstimateSplitResponse{Splits: make([]SplitLeg, len(pools))}
+	total := new(big.Int)
+	for i, pool := range pools {
+		amountOut := calculateSwapAmount(amountsIn[i], reserves[i].ReserveIn, reserves[i].ReserveOut, se.fee)
+		if err := checkAmountOut(amountOut, reserves[i].ReserveOut); err != nil {
+			return nil, fmt.Errorf("pool %s: %w", pool.Hex(), err)
+		}
+		total.Add(total, amountOut)
+
+		response.Splits[i] = SplitLeg{
+			Pool:        pool.Hex(),
+			SrcAmount:   amountsIn[i].String(),
+			DstAmount:   amountOut.String(),
+			PriceImpact: calculatePriceImpact(amountsIn[i], amountOut, reserves[i].ReserveIn, reserves[i].ReserveOut).FloatString(4),
+		}
+	}
+	response.DstAmount = total.String()
+
+	return response, nil
+}
+
+func (se *SwapEstimator) estimateSplitHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	poolsStr := query.Get("pools")
+	srcStr := query.Get("src")
+	dstStr := query.Get("dst")
+	srcAmountStr := query.Get("src_amount")
+
+	if poolsStr == "" || srcStr == "" || dstStr == "" || srcAmountStr == "" {
+		writeError(w, http.StatusBadRequest, CodeMissingParameter, "Missing required parameters: pools, src, dst, src_amount")
+		return
+	}
+
+	pools, err := parseAddressList("pools", poolsStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+	pools = uniqueAddresses(pools)
+
+	if len(pools) != 2 {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "Invalid pools: must list two different pool addresses")
+		return
+	}
+
+	se, err = se.forChain(query.Get("chain_id"))
+	if err != nil {
+		status, message := http.StatusBadRequest, err.Error()
+		if !errors.Is(err, ErrChainNotConfigured) {
+			slog.ErrorContext(r.Context(), "split estimate failed", "pools", poolsStr, "error", err)
+			status, message = http.StatusInternalServerError, "Failed to connect to chain"
+		}
+		writeError(w, status, errorCode(status, err), message)
+		return
+	}
+
+	tokens, err := se.resolveSwapTokens(srcStr, dstStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	srcAmount, err := se.parseSrcAmount(srcAmountStr)
+	if err != nil {
+		writeRequestError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := se.withRPCTimeout(r.Context())
+	defer cancel()
+
+	response, err := se.EstimateSplit(ctx, pools[0], pools[1], tokens.Src, tokens.Dst, srcAmount)
+	if err != nil {
+		slog.WarnContext(ctx, "split estimate failed", "pools", poolsStr, "src", srcStr, "dst", dstStr, "src_amount", srcAmountStr, "error", err)
+		writeEstimateError(w, err)
+		return
+	}
+	response.WrapsETH = tokens.WrapSrc
+	response.UnwrapsWETH = tokens.UnwrapDst
+
+	json.NewEncoder(w).Encode(response)
+}
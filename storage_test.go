@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// testReservesSlot is a slot 8 word laid out as a UniswapV2Pair stores it:
+// blockTimestampLast 0x6553f17b, then reserve1 0x392e115eaa2996993ca
+// and reserve0 0x1c6af2ee4c84, from the high bits down.
+var testReservesSlot = common.HexToHash("0x6553f17b000000000392e115eaa2996993ca00000000000000001c6af2ee4c84")
+
+func TestUnpackReservesSlot(t *testing.T) {
+	tests := []struct {
+		name               string
+		slot               common.Hash
+		reserve0, reserve1 string
+		blockTimestampLast uint32
+	}{
+		{"USDC/WETH-like pair", testReservesSlot, "31245667814532", "16876543210987654321098", 1700000123},
+		{"empty pair", common.Hash{}, "0", "0", 0},
+		{
+			"every field at its maximum",
+			common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+			"5192296858534827628530496329220095", "5192296858534827628530496329220095", 4294967295,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unpackReservesSlot(tt.slot)
+			if got.Reserve0.String() != tt.reserve0 {
+				t.Errorf("Reserve0 = %s, want %s", got.Reserve0, tt.reserve0)
+			}
+			if got.Reserve1.String() != tt.reserve1 {
+				t.Errorf("Reserve1 = %s, want %s", got.Reserve1, tt.reserve1)
+			}
+			if got.BlockTimestampLast != tt.blockTimestampLast {
+				t.Errorf("BlockTimestampLast = %d, want %d", got.BlockTimestampLast, tt.blockTimestampLast)
+			}
+		})
+	}
+}
+
+func TestGetReservesFromStorage(t *testing.T) {
+	node := newMockNode(t)
+	ec := node.client()
+	node.setStorage(testPool, reservesSlot, testReservesSlot)
+
+	got, err := ec.GetReservesFromStorage(context.Background(), testPool, nil)
+	if err != nil {
+		t.Fatalf("GetReservesFromStorage: %v", err)
+	}
+	if got.Reserve0.String() != "31245667814532" || got.Reserve1.String() != "16876543210987654321098" || got.BlockTimestampLast != 1700000123 {
+		t.Errorf("reserves = %s/%s at %d, want 31245667814532/16876543210987654321098 at 1700000123", got.Reserve0, got.Reserve1, got.BlockTimestampLast)
+	}
+}
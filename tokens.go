@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// tokenStringKey identifies a cached symbol() or name() result.
+type tokenStringKey struct {
+	token  common.Address
+	method string
+}
+
+// GetDecimals returns the token's decimals, caching the result since it is
+// immutable for any sane ERC20.
+func (ec *EthereumClient) GetDecimals(ctx context.Context, tokenAddr common.Address) (uint8, error) {
+	ec.decimalsMu.RLock()
+	decimals, ok := ec.decimalsCache[tokenAddr]
+	ec.decimalsMu.RUnlock()
+	if ok {
+		return decimals, nil
+	}
+
+	data, err := ec.erc20ABI.Pack("decimals")
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack decimals call: %w", err)
+	}
+
+	result, err := ec.callContract(ctx, "decimals", tokenAddr, data, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call decimals: %w", err)
+	}
+
+	unpacked, err := ec.erc20ABI.Unpack("decimals", result)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unpack decimals result: %w", err)
+	}
+
+	if len(unpacked) == 0 {
+		return 0, fmt.Errorf("empty decimals result")
+	}
+
+	decimals, ok = unpacked[0].(uint8)
+	if !ok {
+		return 0, fmt.Errorf("failed to cast decimals to uint8")
+	}
+
+	ec.decimalsMu.Lock()
+	ec.decimalsCache[tokenAddr] = decimals
+	ec.decimalsMu.Unlock()
+
+	return decimals, nil
+}
+
+func (ec *EthereumClient) GetPairDecimals(ctx context.Context, tokenA, tokenB common.Address) (uint8, uint8, error) {
+	decimalsA, err := ec.GetDecimals(ctx, tokenA)
+	if err != nil {
+		return 0, 0, fmt.Errorf("token %s: %w", tokenA.Hex(), err)
+	}
+
+	decimalsB, err := ec.GetDecimals(ctx, tokenB)
+	if err != nil {
+		return 0, 0, fmt.Errorf("token %s: %w", tokenB.Hex(), err)
+	}
+
+	return decimalsA, decimalsB, nil
+}
+
+// GetSymbol returns the token's symbol, or "" if it doesn't implement
+// symbol().
+func (ec *EthereumClient) GetSymbol(ctx context.Context, tokenAddr common.Address) (string, error) {
+	return ec.getTokenString(ctx, tokenAddr, "symbol")
+}
+
+// GetName returns the token's name, or "" if it doesn't implement name().
+func (ec *EthereumClient) GetName(ctx context.Context, tokenAddr common.Address) (string, error) {
+	return ec.getTokenString(ctx, tokenAddr, "name")
+}
+
+// getTokenString calls a string getter such as symbol() and caches the
+// result. Tokens that revert or return garbage are cached as "" so they are
+// only asked once; node errors are returned and not cached.
+func (ec *EthereumClient) getTokenString(ctx context.Context, tokenAddr common.Address, method string) (string, error) {
+	key := tokenStringKey{tokenAddr, method}
+
+	ec.tokenStringsMu.RLock()
+	value, ok := ec.tokenStrings[key]
+	ec.tokenStringsMu.RUnlock()
+	if ok {
+		return value, nil
+	}
+
+	data, err := ec.erc20ABI.Pack(method)
+	if err != nil {
+		return "", fmt.Errorf("failed to pack %s call: %w", method, err)
+	}
+
+	result, err := ec.callContract(ctx, method, tokenAddr, data, nil)
+	if err == nil {
+		value, err = ec.decodeTokenString(method, result)
+	}
+	if err != nil {
+		if !isContractFailure(err) {
+			return "", fmt.Errorf("failed to call %s: %w", method, err)
+		}
+		value = ""
+	}
+
+	ec.tokenStringsMu.Lock()
+	ec.tokenStrings[key] = value
+	ec.tokenStringsMu.Unlock()
+
+	return value, nil
+}
+
+// decodeTokenString decodes a string getter's result. Some early tokens,
+// such as MKR, declare symbol() and name() as bytes32 instead of string.
+func (ec *EthereumClient) decodeTokenString(method string, result []byte) (string, error) {
+	if unpacked, err := ec.erc20ABI.Unpack(method, result); err == nil && len(unpacked) > 0 {
+		if value, ok := unpacked[0].(string); ok {
+			return strings.ToValidUTF8(value, ""), nil
+		}
+	}
+
+	if len(result) == 32 {
+		return strings.ToValidUTF8(string(bytes.TrimRight(result, "\x00")), ""), nil
+	}
+
+	return "", fmt.Errorf("%w: failed to unpack %s result", errMalformedResult, method)
+}
+
+// tokenSymbols looks up both tokens' symbols for include_metadata. A symbol
+// that can't be fetched is left empty rather than failing the estimate.
+func (se *SwapEstimator) tokenSymbols(ctx context.Context, tokens swapTokens) (string, string) {
+	symbols := make([]string, 2)
+	for i, token := range []common.Address{tokens.Src, tokens.Dst} {
+		symbol, err := se.ethClient.GetSymbol(ctx, token)
+		if err != nil {
+			slog.DebugContext(ctx, "symbol lookup failed", "token", token.Hex(), "error", err)
+		}
+		symbols[i] = symbol
+	}
+	return symbols[0], symbols[1]
+}
+
+// defaultDecimalPrecision is the number of decimal places format=decimal
+// shows unless the request sets precision.
+const defaultDecimalPrecision = 6
+
+// formatUnits renders a raw token amount as a decimal string with at most
+// precision decimal places, e.g. 1234567800 with 6 decimals becomes
+// "1234.5678", or "1234.56" with precision 2. Extra digits are truncated
+// (rounded toward zero) so an output is never overstated, and trailing zeros
+// are trimmed.
+func formatUnits(amount *big.Int, decimals uint8, precision int) string {
+	if precision < int(decimals) {
+		amount = new(big.Int).Quo(amount, pow10(decimals-uint8(precision)))
+		decimals = uint8(precision)
+	}
+
+	if decimals == 0 {
+		return amount.String()
+	}
+
+	whole, frac := new(big.Int).QuoRem(new(big.Int).Abs(amount), pow10(decimals), new(big.Int))
+
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+
+	if frac.Sign() == 0 {
+		return sign + whole.String()
+	}
+
+	fracStr := fmt.Sprintf("%0*s", int(decimals), frac.String())
+	return sign + whole.String() + "." + strings.TrimRight(fracStr, "0")
+}
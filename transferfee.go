@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// transferProbeRecipient is an address no token should special-case, used as
+// the receiver of simulated transfers.
+var transferProbeRecipient = common.BytesToAddress(crypto.Keccak256([]byte("uniswap-v2-estimator.transfer-probe")))
+
+// getMulticallCode returns Multicall3's runtime bytecode, caching it since
+// it never changes. It is nil when Multicall3 isn't deployed.
+func (ec *EthereumClient) getMulticallCode(ctx context.Context) ([]byte, error) {
+	ec.multicallCodeMu.Lock()
+	defer ec.multicallCodeMu.Unlock()
+
+	if ec.multicallCode != nil {
+		return ec.multicallCode, nil
+	}
+
+	code, err := ec.conn().CodeAt(ctx, multicall3Address, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get multicall code: %w", err)
+	}
+	if len(code) == 0 {
+		return nil, nil
+	}
+
+	ec.multicallCode = code
+	return code, nil
+}
+
+// SimulateTransfer returns how much of amount a fresh address receives when
+// pool sends it amount of token. The call runs against pool with its code
+// replaced by Multicall3's, so the transfer comes from the pool's own balance
+// and no state changes. A result below amount means token takes a fee on
+// transfer.
+func (ec *EthereumClient) SimulateTransfer(ctx context.Context, pool, token common.Address, amount, blockNumber *big.Int) (*big.Int, error) {
+	code, err := ec.getMulticallCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if code == nil {
+		return nil, fmt.Errorf("transfer simulation requires Multicall3, which isn't deployed on this chain")
+	}
+
+	balanceOf, err := ec.erc20ABI.Pack("balanceOf", transferProbeRecipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack balanceOf call: %w", err)
+	}
+
+	transfer, err := ec.erc20ABI.Pack("transfer", transferProbeRecipient, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack transfer call: %w", err)
+	}
+
+	data, err := ec.multicallABI.Pack("aggregate3", []multicall3Call{
+		{Target: token, CallData: balanceOf},
+		{Target: token, AllowFailure: true, CallData: transfer},
+		{Target: token, CallData: balanceOf},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack aggregate3 call: %w", err)
+	}
+
+	callArgs := map[string]any{
+		"to":   pool,
+		"data": hexutil.Bytes(data),
+	}
+
+	blockNumber = ec.blockOrDefault(blockNumber)
+	block := rpc.LatestBlockNumber
+	if blockNumber != nil {
+		block = rpc.BlockNumber(blockNumber.Int64())
+	}
+
+	// ethclient has no state override support, so call eth_call directly
+	overrides := map[common.Address]map[string]any{
+		pool: {"code": hexutil.Bytes(code)},
+	}
+
+	if err := ec.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	result, err := withRetry(ctx, ec.maxRetries, func() ([]byte, error) {
+		start := time.Now()
+		client := ec.conn()
+		var result hexutil.Bytes
+		err := client.Client().CallContext(ctx, &result, "eth_call", callArgs, block, overrides)
+		observeRPCCall("simulateTransfer", start, err)
+		ec.checkConnection(client, err)
+		return result, err
+	})
+	ec.breaker.Record(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate transfer: %w", err)
+	}
+
+	unpacked, err := ec.multicallABI.Unpack("aggregate3", result)
+	if err != nil || len(unpacked) == 0 {
+		return nil, fmt.Errorf("%w: failed to unpack transfer simulation", errMalformedResult)
+	}
+
+	results := *abi.ConvertType(unpacked[0], new([]multicall3Result)).(*[]multicall3Result)
+	if len(results) != 3 {
+		return nil, fmt.Errorf("%w: unexpected transfer simulation result length %d", errMalformedResult, len(results))
+	}
+	if !results[1].Success {
+		return nil, fmt.Errorf("simulated transfer of %s reverted", token.Hex())
+	}
+
+	before := new(big.Int).SetBytes(results[0].ReturnData)
+	after := new(big.Int).SetBytes(results[2].ReturnData)
+	return after.Sub(after, before), nil
+}
+
+// DetectTransferFeeBps simulates the pool sending amount of token and returns
+// the fee the token withheld, in basis points. Tokens that tax buys and sells
+// differently are only measured on the pool-to-trader side.
+func (se *SwapEstimator) DetectTransferFeeBps(ctx context.Context, pool, token common.Address, amount, blockNumber *big.Int) (int64, error) {
+	if amount.Sign() <= 0 {
+		return 0, nil
+	}
+
+	received, err := se.ethClient.SimulateTransfer(ctx, pool, token, amount, blockNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	if received.Cmp(amount) >= 0 {
+		return 0, nil
+	}
+
+	withheld := new(big.Int).Sub(amount, received)
+	feeBps := withheld.Mul(withheld, big.NewInt(10000))
+	return feeBps.Div(feeBps, amount).Int64(), nil
+}
+
+// applyTransferFee returns the part of amount left after a transfer fee of
+// feeBps.
+func applyTransferFee(amount *big.Int, feeBps int64) *big.Int {
+	received := new(big.Int).Mul(amount, big.NewInt(10000-feeBps))
+	return received.Div(received, big.NewInt(10000))
+}
+
+// transferFeeWarnings simulates the pool sending each side of the swap and
+// describes any fee-on-transfer behaviour found. A failed simulation is
+// reported as a warning rather than failing the estimate.
+func (se *SwapEstimator) transferFeeWarnings(ctx context.Context, params *estimateParams, estimate *SwapEstimate) []string {
+	// The pool can only send what it holds
+	srcProbeAmount := params.srcAmount
+	if srcProbeAmount.Cmp(estimate.ReserveIn) >= 0 {
+		srcProbeAmount = new(big.Int).Div(estimate.ReserveIn, big.NewInt(2))
+	}
+
+	var warnings []string
+	for _, side := range []struct {
+		name   string
+		token  common.Address
+		amount *big.Int
+	}{
+		{"src", params.tokens.Src, srcProbeAmount},
+		{"dst", params.tokens.Dst, estimate.AmountOut},
+	} {
+		feeBps, err := se.DetectTransferFeeBps(ctx, params.pool, side.token, side.amount, params.opts.BlockNumber)
+		if err != nil {
+			slog.DebugContext(ctx, "transfer fee check failed", "token", side.token.Hex(), "error", err)
+			warnings = append(warnings, fmt.Sprintf("Could not check %s token %s for a transfer fee", side.name, side.token.Hex()))
+			continue
+		}
+		if feeBps > 0 {
+			warnings = append(warnings, fmt.Sprintf("%s token %s withheld %d bps on a simulated transfer; it appears to be fee-on-transfer", side.name, side.token.Hex(), feeBps))
+		}
+	}
+
+	return warnings
+}
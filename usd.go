@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var errNoUSDRoute = errors.New("neither token is WETH or the USD_PRICE_POOL stablecoin")
+
+// SetUSDPricePool sets the WETH/stablecoin pair that liquidity_usd is priced
+// against. The zero address disables it.
+func (se *SwapEstimator) SetUSDPricePool(pool common.Address) {
+	se.usdPricePool = pool
+}
+
+// usdReference is what USD_PRICE_POOL says about the dollar at a block.
+type usdReference struct {
+	stable         common.Address
+	stableDecimals uint8
+	wethDecimals   uint8
+	// wethPrice is the USD price of one whole WETH
+	wethPrice *big.Rat
+}
+
+// readUSDReference reads USD_PRICE_POOL, whose tokens are WETH and a USD
+// stablecoin.
+func (se *SwapEstimator) readUSDReference(ctx context.Context, blockNumber *big.Int) (*usdReference, error) {
+	// Read directly rather than through GetPoolState, so POOL_ALLOWLIST
+	// doesn't have to list the reference pool
+	token0, err := se.ethClient.GetToken0(ctx, se.usdPricePool, blockNumber)
+	if err != nil {
+		return nil, pairCallError(se.usdPricePool, "token0", err)
+	}
+	token1, err := se.ethClient.GetToken1(ctx, se.usdPricePool, blockNumber)
+	if err != nil {
+		return nil, pairCallError(se.usdPricePool, "token1", err)
+	}
+	reserves, err := se.ethClient.GetReserves(ctx, se.usdPricePool, blockNumber)
+	if err != nil {
+		return nil, pairCallError(se.usdPricePool, "reserves", err)
+	}
+
+	stable, stableReserve, wethReserve := token0, reserves.Reserve0, reserves.Reserve1
+	switch se.weth {
+	case token1:
+	case token0:
+		stable, stableReserve, wethReserve = token1, reserves.Reserve1, reserves.Reserve0
+	default:
+		return nil, fmt.Errorf("USD_PRICE_POOL %s is not a WETH pair", se.usdPricePool.Hex())
+	}
+
+	stableDecimals, wethDecimals, err := se.ethClient.GetPairDecimals(ctx, stable, se.weth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch decimals: %w", err)
+	}
+
+	return &usdReference{
+		stable:         stable,
+		stableDecimals: stableDecimals,
+		wethDecimals:   wethDecimals,
+		wethPrice:      normalizedRatio(stableReserve, wethReserve, stableDecimals, wethDecimals),
+	}, nil
+}
+
+// LiquidityUSD approximates a pool's value in USD as twice the value of the
+// side held in the reference stablecoin or, failing that, in WETH. It assumes
+// the pool is balanced, as arbitrage keeps V2 pools.
+func (se *SwapEstimator) LiquidityUSD(ctx context.Context, token0, token1 common.Address, reserve0, reserve1, blockNumber *big.Int) (*big.Rat, error) {
+	ref, err := se.readUSDReference(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var side *big.Rat
+	switch {
+	case token0 == ref.stable:
+		side = new(big.Rat).SetFrac(reserve0, pow10(ref.stableDecimals))
+	case token1 == ref.stable:
+		side = new(big.Rat).SetFrac(reserve1, pow10(ref.stableDecimals))
+	case token0 == se.weth:
+		side = new(big.Rat).SetFrac(reserve0, pow10(ref.wethDecimals))
+		side.Mul(side, ref.wethPrice)
+	case token1 == se.weth:
+		side = new(big.Rat).SetFrac(reserve1, pow10(ref.wethDecimals))
+		side.Mul(side, ref.wethPrice)
+	default:
+		return nil, errNoUSDRoute
+	}
+
+	return side.Mul(side, big.NewRat(2, 1)), nil
+}
+
+// setLiquidityUSD adds liquidity_usd to the response. A pool that can't be
+// valued gets a warning rather than failing the estimate.
+func (se *SwapEstimator) setLiquidityUSD(ctx context.Context, params *estimateParams, estimate *SwapEstimate, response *EstimateResponse) {
+	reserve0, reserve1 := estimate.ReserveOut, estimate.ReserveIn
+	if estimate.ZeroForOne {
+		reserve0, reserve1 = estimate.ReserveIn, estimate.ReserveOut
+	}
+
+	liquidity, err := se.LiquidityUSD(ctx, estimate.Token0, estimate.Token1, reserve0, reserve1, params.opts.BlockNumber)
+	if err != nil {
+		slog.DebugContext(ctx, "USD liquidity valuation failed", "pool", params.pool.Hex(), "error", err)
+		response.Warnings = append(response.Warnings, fmt.Sprintf("Could not value the pool in USD: %v", err))
+		return
+	}
+	response.LiquidityUSD = liquidity.FloatString(2)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WETH9 on Ethereum mainnet
+var defaultWETHAddress = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+
+var errSameToken = errors.New("src and dst must differ")
+
+// swapTokens is the pair of ERC20 tokens a swap actually trades, after native
+// ETH has been replaced with WETH.
+type swapTokens struct {
+	Src common.Address
+	Dst common.Address
+	// WrapSrc means the caller's ETH must be wrapped before the swap
+	WrapSrc bool
+	// UnwrapDst means the WETH output must be unwrapped to ETH after the swap
+	UnwrapDst bool
+}
+
+func (se *SwapEstimator) SetWETH(wethAddr common.Address) {
+	se.weth = wethAddr
+}
+
+// isNativeETH reports whether a src/dst parameter refers to native ETH,
+// written either as "ETH" or as the zero address.
+func isNativeETH(s string) bool {
+	if strings.EqualFold(s, "ETH") {
+		return true
+	}
+	return common.IsHexAddress(s) && common.HexToAddress(s) == (common.Address{})
+}
+
+// resolveSwapTokens parses src and dst, mapping native ETH to WETH. An empty
+// dst leaves Dst unset, for callers that infer it from the pool.
+func (se *SwapEstimator) resolveSwapTokens(src, dst string) (swapTokens, error) {
+	tokens := swapTokens{
+		WrapSrc:   isNativeETH(src),
+		UnwrapDst: isNativeETH(dst),
+	}
+
+	if !tokens.WrapSrc {
+		addr, err := parseAddress("src", src)
+		if err != nil {
+			return swapTokens{}, err
+		}
+		tokens.Src = addr
+	}
+
+	if !tokens.UnwrapDst && dst != "" {
+		addr, err := parseAddress("dst", dst)
+		if err != nil {
+			return swapTokens{}, err
+		}
+		tokens.Dst = addr
+	}
+
+	if tokens.WrapSrc && tokens.UnwrapDst {
+		return swapTokens{}, withCode(CodeSameToken, errors.New("src and dst cannot both be ETH"))
+	}
+
+	if tokens.WrapSrc || tokens.UnwrapDst {
+		if se.weth == (common.Address{}) {
+			return swapTokens{}, fmt.Errorf("ETH is not supported on this chain: no WETH address configured")
+		}
+
+		if tokens.WrapSrc {
+			tokens.Src = se.weth
+		}
+		if tokens.UnwrapDst {
+			tokens.Dst = se.weth
+		}
+	}
+
+	// Also catches ETH paired with WETH, which is a wrap rather than a swap
+	if dst != "" && tokens.Src == tokens.Dst {
+		return swapTokens{}, errSameToken
+	}
+
+	return tokens, nil
+}
+
+// inferDst sets params' dst to whichever of the pool's tokens isn't src, when
+// the request left dst out. It returns the pool state it read, at
+// params.opts.BlockNumber, so the estimate can reuse it.
+func (se *SwapEstimator) inferDst(ctx context.Context, params *estimateParams) (*PoolState, error) {
+	state, err := se.GetPoolState(ctx, params.pool, params.opts.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	switch params.tokens.Src {
+	case state.Token0:
+		params.tokens.Dst = state.Token1
+	case state.Token1:
+		params.tokens.Dst = state.Token0
+	default:
+		return nil, fmt.Errorf("%w: src %s is neither token0 %s nor token1 %s", ErrTokenMismatch, params.tokens.Src.Hex(), state.Token0.Hex(), state.Token1.Hex())
+	}
+	params.inferDst = false
+	return state, nil
+}
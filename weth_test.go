@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestEstimateHandlerInfersDst(t *testing.T) {
+	chain := newFakeChain(map[common.Address]fakePair{
+		testPool: {token0: testToken0, token1: testToken1, reserves: testReserves(t)},
+	})
+	se := NewSwapEstimator(chain)
+
+	req := httptest.NewRequest(http.MethodGet, "/estimate?pool="+testPool.Hex()+"&src="+testToken0.Hex()+"&src_amount=1000000000000000000", nil)
+	rec := httptest.NewRecorder()
+	se.estimateHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	var resp EstimateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Dst != testToken1.Hex() {
+		t.Errorf("dst = %s, want %s", resp.Dst, testToken1.Hex())
+	}
+	if resp.DstAmount != "1974316068794122597" {
+		t.Errorf("dst_amount = %s, want 1974316068794122597", resp.DstAmount)
+	}
+	// The state read to infer dst is reused for the estimate
+	if reads := chain.reserveReads.Load(); reads != 1 {
+		t.Errorf("read reserves %d times, want 1", reads)
+	}
+}
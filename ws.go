@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// blockPollInterval is how often /ws/quote checks for new blocks when the
+// node doesn't support subscriptions (e.g. an HTTP endpoint).
+const blockPollInterval = 2 * time.Second
+
+type QuoteUpdate struct {
+	BlockNumber uint64 `json:"block_number"`
+	DstAmount   string `json:"dst_amount,omitempty"`
+	PriceImpact string `json:"price_impact,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Code        string `json:"code,omitempty"`
+}
+
+// quoteStreamHandler serves /ws/quote, which pushes a fresh estimate to the
+// client for every new block. Browser connections are only accepted from the
+// CORS allowed origins.
+func (se *SwapEstimator) quoteStreamHandler(allowedOrigins []string) http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || slices.Contains(allowedOrigins, "*") || slices.Contains(allowedOrigins, origin)
+		},
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		req := EstimateRequest{
+			Pool:      query.Get("pool"),
+			Src:       query.Get("src"),
+			Dst:       query.Get("dst"),
+			SrcAmount: query.Get("src_amount"),
+			FeeBps:    query.Get("fee_bps"),
+			ChainID:   query.Get("chain_id"),
+			NodeURL:   query.Get("node_url"),
+		}
+
+		params, status, err := se.parseEstimateRequest(req)
+		if err != nil {
+			if status >= http.StatusInternalServerError {
+				slog.ErrorContext(r.Context(), "quote stream failed", "pool", req.Pool, "error", err)
+				err = errors.New("Failed to connect to chain")
+			}
+			writeRequestError(w, status, err)
+			return
+		}
+		defer params.Close()
+		se := params.estimator
+
+		// Reject bad pools and tokens with a normal HTTP error before upgrading
+		var (
+			firstBlock *big.Int
+			firstState *PoolState
+		)
+		if params.inferDst {
+			firstBlock, firstState, err = se.inferStreamDst(r.Context(), params)
+			if err != nil {
+				writeEstimateError(w, err)
+				return
+			}
+		}
+		first, err := se.quoteUpdate(r.Context(), params, firstBlock, firstState)
+		if err != nil {
+			writeEstimateError(w, err)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade has already replied to the client
+			slog.WarnContext(r.Context(), "websocket upgrade failed", "error", err)
+			return
+		}
+		defer conn.Close()
+
+		// Outlive the handshake request but keep its values, e.g. the request ID
+		ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+		defer cancel()
+
+		// The client never sends data, but reading is how a disconnect is noticed
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		if err := conn.WriteJSON(first); err != nil {
+			return
+		}
+		lastBlock := first.BlockNumber
+
+		blocks := make(chan uint64)
+		watchErr := make(chan error, 1)
+		go func() {
+			watchErr <- se.watchBlocks(ctx, blocks)
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-watchErr:
+				if err == nil {
+					return
+				}
+				slog.WarnContext(ctx, "block watch failed", "pool", req.Pool, "error", err)
+				conn.WriteJSON(QuoteUpdate{Error: "Lost connection to the Ethereum node", Code: CodeRPCError})
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, ""))
+				return
+			case blockNumber := <-blocks:
+				if blockNumber <= lastBlock {
+					continue
+				}
+				lastBlock = blockNumber
+
+				update, err := se.quoteUpdate(ctx, params, new(big.Int).SetUint64(blockNumber), nil)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					slog.WarnContext(ctx, "quote stream estimate failed", "pool", req.Pool, "block", blockNumber, "error", err)
+					update = &QuoteUpdate{BlockNumber: blockNumber, Error: estimateErrorMessage(err), Code: errorCode(estimateErrorStatus(err), err)}
+				}
+				if err := conn.WriteJSON(update); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// inferStreamDst infers params' dst at the latest block, returning that block
+// and the pool state read there for the stream's first update.
+func (se *SwapEstimator) inferStreamDst(ctx context.Context, params *estimateParams) (*big.Int, *PoolState, error) {
+	ctx, cancel := se.withRPCTimeout(ctx)
+	defer cancel()
+
+	latest, err := se.ethClient.BlockNumber(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to get block number: %w", ErrRPCFailure, err)
+	}
+	blockNumber := new(big.Int).SetUint64(latest)
+
+	// Every update sets its own block, so the stream doesn't read this again
+	params.opts.BlockNumber = blockNumber
+	state, err := se.inferDst(ctx, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return blockNumber, state, nil
+}
+
+// quoteUpdate estimates the swap described by params at blockNumber, or at
+// the latest block when blockNumber is nil. state, when not nil, is the
+// pool's state at blockNumber.
+func (se *SwapEstimator) quoteUpdate(ctx context.Context, params *estimateParams, blockNumber *big.Int, state *PoolState) (*QuoteUpdate, error) {
+	ctx, cancel := se.withRPCTimeout(ctx)
+	defer cancel()
+
+	if blockNumber == nil {
+		latest, err := se.ethClient.BlockNumber(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to get block number: %w", ErrRPCFailure, err)
+		}
+		blockNumber = new(big.Int).SetUint64(latest)
+	}
+
+	opts := params.opts
+	opts.BlockNumber = blockNumber
+	opts.PoolState = state
+
+	estimate, err := se.EstimateSwapWithOptions(ctx, params.pool, params.tokens.Src, params.tokens.Dst, params.srcAmount, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QuoteUpdate{
+		BlockNumber: blockNumber.Uint64(),
+		DstAmount:   estimate.AmountOut.String(),
+		PriceImpact: estimate.PriceImpact.FloatString(4),
+	}, nil
+}
+
+// watchBlocks sends the number of every new block to blocks until ctx is
+// done. It subscribes to new heads when the node supports it and otherwise
+// polls every blockPollInterval.
+func (se *SwapEstimator) watchBlocks(ctx context.Context, blocks chan<- uint64) error {
+	headers := make(chan *types.Header)
+	sub, err := se.ethClient.SubscribeNewHead(ctx, headers)
+	if errors.Is(err, rpc.ErrNotificationsUnsupported) {
+		return se.pollBlocks(ctx, blocks)
+	}
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-sub.Err():
+			return err
+		case header := <-headers:
+			select {
+			case blocks <- header.Number.Uint64():
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func (se *SwapEstimator) pollBlocks(ctx context.Context, blocks chan<- uint64) error {
+	ticker := time.NewTicker(blockPollInterval)
+	defer ticker.Stop()
+
+	var last uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		callCtx, cancel := se.withRPCTimeout(ctx)
+		blockNumber, err := se.ethClient.BlockNumber(callCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			slog.WarnContext(ctx, "failed to poll block number", "error", err)
+			continue
+		}
+
+		if blockNumber <= last {
+			continue
+		}
+		last = blockNumber
+
+		select {
+		case blocks <- blockNumber:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}